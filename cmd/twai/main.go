@@ -40,8 +40,12 @@ func newCommand() *ffcli.Command {
 	cmds := []*ffcli.Command{
 		newVersionCommand(),
 		newScrapeCommand(),
+		newThreadCommand(),
+		newUsersCommand(),
+		newFollowersCommand(),
 		newScoreCommand(),
 		newEloCommand(),
+		newEvalCommand(),
 	}
 	port := fs.Int("port", 0, "port number")
 
@@ -101,10 +105,19 @@ func newScrapeCommand() *ffcli.Command {
 
 	var cfg twai.ScrapeConfig
 	fs.BoolVar(&cfg.Debug, "debug", false, "debug mode")
-	fs.StringVar(&cfg.Page, "page", "home", "page to fetch (home or username)")
+	fs.StringVar(&cfg.Page, "page", "home", "page to fetch (home, bookmarks, bookmarks:<id>, list:<id>, likes:<user>, username or path)")
+	fs.StringVar(&cfg.Search, "search", "", "search query to fetch (overrides page)")
+	fs.StringVar(&cfg.SearchTab, "search-tab", "latest", "search tab (latest or top)")
 	fs.IntVar(&cfg.N, "n", 50, "number of tweets to fetch")
 	fs.BoolVar(&cfg.Followers, "followers", false, "fetch followers stats")
-	fs.StringVar(&cfg.Output, "output", "", "output file")
+	fs.BoolVar(&cfg.SkipRetweets, "skip-retweets", false, "skip retweets of other accounts")
+	fs.StringVar(&cfg.SinceID, "since-id", "", "stop when tweets older than this id are found")
+	fs.StringVar(&cfg.Since, "since", "", "stop when tweets older than this time are found (2006-01-02 or RFC3339)")
+	fs.StringVar(&cfg.Until, "until", "", "skip tweets newer than this time (2006-01-02 or RFC3339)")
+	fs.StringVar(&cfg.Watermark, "watermark", "", "file to store the newest tweet id, used as since-id in the next run")
+	fs.StringVar(&cfg.Output, "output", "", "output file (stdout if empty)")
+	fs.StringVar(&cfg.Format, "format", "", "output format (csv, json or jsonl), obtained from the output extension if empty")
+	fs.StringVar(&cfg.DB, "db", "", "sqlite database to store tweets (e.g. twai.db)")
 	fs.BoolVar(&cfg.ShowBrowser, "show-browser", false, "show browser")
 	fs.StringVar(&cfg.CookieFile, "cookie-file", "cookie.txt", "cookie file")
 
@@ -124,6 +137,97 @@ func newScrapeCommand() *ffcli.Command {
 	}
 }
 
+func newThreadCommand() *ffcli.Command {
+	cmd := "thread"
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	_ = fs.String("config", "", "config file (optional)")
+
+	var cfg twai.ThreadConfig
+	fs.BoolVar(&cfg.Debug, "debug", false, "debug mode")
+	fs.StringVar(&cfg.URL, "url", "", "tweet url (https://x.com/user/status/id)")
+	fs.IntVar(&cfg.N, "n", 100, "number of tweets to fetch, including the replies")
+	fs.StringVar(&cfg.Output, "output", "", "output file (stdout if empty)")
+	fs.StringVar(&cfg.Format, "format", "", "output format (csv, json or jsonl), obtained from the output extension if empty")
+	fs.BoolVar(&cfg.ShowBrowser, "show-browser", false, "show browser")
+	fs.StringVar(&cfg.CookieFile, "cookie-file", "cookie.txt", "cookie file")
+
+	return &ffcli.Command{
+		Name:       cmd,
+		ShortUsage: fmt.Sprintf("twai %s [flags] <key> <value data...>", cmd),
+		Options: []ff.Option{
+			ff.WithConfigFileFlag("config"),
+			ff.WithConfigFileParser(ffyaml.Parser),
+			ff.WithEnvVarPrefix("twai"),
+		},
+		ShortHelp: fmt.Sprintf("twai %s command", cmd),
+		FlagSet:   fs,
+		Exec: func(ctx context.Context, args []string) error {
+			return twai.Thread(ctx, &cfg)
+		},
+	}
+}
+
+func newUsersCommand() *ffcli.Command {
+	cmd := "users"
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	_ = fs.String("config", "", "config file (optional)")
+
+	var cfg twai.UsersConfig
+	fs.BoolVar(&cfg.Debug, "debug", false, "debug mode")
+	fs.StringVar(&cfg.Handles, "handles", "", "comma separated list of user handles")
+	fs.StringVar(&cfg.Input, "input", "", "input file to fetch the authors of the tweets (generated by scrape command, csv, json or jsonl)")
+	fs.StringVar(&cfg.Output, "output", "", "output file (stdout if empty)")
+	fs.StringVar(&cfg.Format, "format", "", "output format (csv, json or jsonl), obtained from the output extension if empty")
+	fs.BoolVar(&cfg.ShowBrowser, "show-browser", false, "show browser")
+	fs.StringVar(&cfg.CookieFile, "cookie-file", "cookie.txt", "cookie file")
+
+	return &ffcli.Command{
+		Name:       cmd,
+		ShortUsage: fmt.Sprintf("twai %s [flags] <key> <value data...>", cmd),
+		Options: []ff.Option{
+			ff.WithConfigFileFlag("config"),
+			ff.WithConfigFileParser(ffyaml.Parser),
+			ff.WithEnvVarPrefix("twai"),
+		},
+		ShortHelp: fmt.Sprintf("twai %s command", cmd),
+		FlagSet:   fs,
+		Exec: func(ctx context.Context, args []string) error {
+			return twai.Users(ctx, &cfg)
+		},
+	}
+}
+
+func newFollowersCommand() *ffcli.Command {
+	cmd := "followers"
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	_ = fs.String("config", "", "config file (optional)")
+
+	var cfg twai.FollowersConfig
+	fs.BoolVar(&cfg.Debug, "debug", false, "debug mode")
+	fs.StringVar(&cfg.Handle, "handle", "", "user handle")
+	fs.BoolVar(&cfg.Following, "following", false, "fetch following users instead of followers")
+	fs.IntVar(&cfg.N, "n", 100, "number of users to fetch")
+	fs.StringVar(&cfg.Output, "output", "", "output file (stdout if empty)")
+	fs.StringVar(&cfg.Format, "format", "", "output format (csv, json or jsonl), obtained from the output extension if empty")
+	fs.BoolVar(&cfg.ShowBrowser, "show-browser", false, "show browser")
+	fs.StringVar(&cfg.CookieFile, "cookie-file", "cookie.txt", "cookie file")
+
+	return &ffcli.Command{
+		Name:       cmd,
+		ShortUsage: fmt.Sprintf("twai %s [flags] <key> <value data...>", cmd),
+		Options: []ff.Option{
+			ff.WithConfigFileFlag("config"),
+			ff.WithConfigFileParser(ffyaml.Parser),
+			ff.WithEnvVarPrefix("twai"),
+		},
+		ShortHelp: fmt.Sprintf("twai %s command", cmd),
+		FlagSet:   fs,
+		Exec: func(ctx context.Context, args []string) error {
+			return twai.Followers(ctx, &cfg)
+		},
+	}
+}
+
 func newScoreCommand() *ffcli.Command {
 	cmd := "score"
 	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
@@ -132,12 +236,27 @@ func newScoreCommand() *ffcli.Command {
 	var cfg twai.ScoreConfig
 	fs.BoolVar(&cfg.Debug, "debug", false, "debug mode")
 	fs.IntVar(&cfg.Concurrency, "concurrency", 1, "number of concurrent requests")
-	fs.StringVar(&cfg.Input, "input", "", "input file (generated by scrape command)")
-	fs.StringVar(&cfg.Output, "output", "", "output file (csv)")
-	fs.StringVar(&cfg.Prompt, "prompt", "Rate the following tweet from 1 to 10 based on relevance, clarity, engagement, and impact. Only answer with a number.", "prompt")
+	fs.StringVar(&cfg.Input, "input", "", "input file in csv, json or jsonl format (generated by scrape command, stdin if empty and no db is provided)")
+	fs.BoolVar(&cfg.Users, "users", false, "input contains users (generated by users or followers commands) to score accounts by their bio")
+	fs.StringVar(&cfg.Output, "output", "", "output file (stdout if empty)")
+	fs.StringVar(&cfg.Format, "format", "", "output format (csv, json or jsonl), obtained from the output extension if empty")
+	fs.StringVar(&cfg.DB, "db", "", "sqlite database to read tweets from and store scores (e.g. twai.db)")
+	fs.StringVar(&cfg.Prompt, "prompt", twai.DefaultScorePrompt, "prompt, a text/template with access to tweet fields (e.g. {{.Text}}, {{.UserName}}, {{.Likes}}), the tweet text is appended if no fields are used")
+	fs.StringVar(&cfg.PromptFile, "prompt-file", "", "file with the prompt template (overrides prompt)")
+	fs.BoolVar(&cfg.Structured, "structured", false, "ask for a json answer with the score of each criterion and a rationale")
+	fs.StringVar(&cfg.Rubric, "rubric", "", "yaml rubric file with the criteria, weights and examples (overrides prompt and enables structured mode)")
+	fs.StringVar(&cfg.Examples, "examples", "", "file with tweets labelled with a human score, used as few-shot examples")
+	fs.BoolVar(&cfg.LogProbs, "logprobs", false, "use the token probabilities of the answers to obtain an expected score and its confidence (not compatible with structured)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", "", "directory of the cache of ai responses (user cache dir if empty)")
+	fs.BoolVar(&cfg.NoCache, "no-cache", false, "disable the cache of ai responses")
 	fs.StringVar(&cfg.Model, "model", "llama3", "ai model (llama3, gpt-3.5-turbo, etc)")
 	fs.StringVar(&cfg.Host, "host", "http://localhost:11434/v1", "ai endpoint host (not needed for openai)")
 	fs.StringVar(&cfg.Token, "token", "", "authorization token (required for openai)")
+	fs.StringVar(&cfg.Models, "models", "", "comma separated list of ai models to use as an ensemble (overrides model)")
+	fs.StringVar(&cfg.Hosts, "hosts", "", "comma separated list of ai endpoint hosts, matched with models by position (a single host is used for all models, empty values use host)")
+	fs.StringVar(&cfg.Tokens, "tokens", "", "comma separated list of authorization tokens, matched with models by position (a single token is used for all models, empty values use token)")
+	fs.StringVar(&cfg.Ensemble, "ensemble", "mean", "method to combine the scores of the models (mean, median or vote)")
+	fs.Float64Var(&cfg.Disagreement, "disagreement", 2, "report tweets whose scores have a standard deviation between models equal or above this value")
 
 	return &ffcli.Command{
 		Name:       cmd,
@@ -163,10 +282,26 @@ func newEloCommand() *ffcli.Command {
 	var cfg twai.EloConfig
 	fs.BoolVar(&cfg.Debug, "debug", false, "debug mode")
 	fs.IntVar(&cfg.Concurrency, "concurrency", 1, "number of concurrent requests")
-	fs.StringVar(&cfg.Input, "input", "", "input file (generated by scrape command)")
-	fs.StringVar(&cfg.Output, "output", "", "output file (csv)")
-	fs.IntVar(&cfg.Iterations, "iterations", 10, "number of iterations")
-	fs.StringVar(&cfg.Prompt, "prompt", "Which tweet is best based on relevance, clarity, engagement, and impact.? 1 or 2? Answer only with the number 1 or 2.", "prompt")
+	fs.StringVar(&cfg.Input, "input", "", "input file in csv, json or jsonl format (generated by scrape command, stdin if empty and no db is provided)")
+	fs.BoolVar(&cfg.Users, "users", false, "input contains users (generated by users or followers commands) to score accounts by their bio")
+	fs.StringVar(&cfg.Output, "output", "", "output file (stdout if empty)")
+	fs.StringVar(&cfg.Format, "format", "", "output format (csv, json or jsonl), obtained from the output extension if empty")
+	fs.StringVar(&cfg.DB, "db", "", "sqlite database to read tweets from and store scores (e.g. twai.db)")
+	fs.IntVar(&cfg.Budget, "budget", 0, "total number of comparisons (10 per tweet if 0)")
+	fs.StringVar(&cfg.Pairing, "pairing", "random", "pairing strategy (random, swiss or active)")
+	fs.Float64Var(&cfg.Converge, "converge", 0.9, "stop when the kendall tau of the top tweets between rounds reaches this value (0 to disable)")
+	fs.IntVar(&cfg.TopK, "top-k", 20, "number of top tweets used to check convergence (0 for all)")
+	fs.IntVar(&cfg.Patience, "patience", 3, "number of consecutive converged rounds needed to stop")
+	fs.StringVar(&cfg.Prompt, "prompt", twai.DefaultEloPrompt, "prompt, a text/template with access to both tweets (e.g. {{.Tweet1.Text}}, {{.Tweet2.UserName}}), the tweets are appended if no fields are used")
+	fs.StringVar(&cfg.PromptFile, "prompt-file", "", "file with the prompt template (overrides prompt)")
+	fs.BoolVar(&cfg.Validate, "validate", false, "only report how often the model picks each tweet position, without writing ratings")
+	fs.StringVar(&cfg.Order, "order", "random", "tweet order in comparisons (fixed, random or both), both asks in both orders and treats inconsistent answers as a draw")
+	fs.StringVar(&cfg.Examples, "examples", "", "file with pairs of tweets labelled with a human score, the highest score is the winner, used as few-shot examples")
+	fs.BoolVar(&cfg.LogProbs, "logprobs", false, "use the token probabilities of answering 1 or 2 as a soft outcome")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", "", "directory of the cache of ai responses (user cache dir if empty)")
+	fs.BoolVar(&cfg.NoCache, "no-cache", false, "disable the cache of ai responses")
+	fs.StringVar(&cfg.Algorithm, "algorithm", "elo", "ranking algorithm (elo, glicko2, trueskill or bradley-terry)")
+	fs.Float64Var(&cfg.K, "k", 32, "k-factor of the elo algorithm")
 	fs.StringVar(&cfg.Model, "model", "llama3", "ai model (llama3, gpt-3.5-turbo, etc)")
 	fs.StringVar(&cfg.Host, "host", "http://localhost:11434/v1", "ai endpoint host (not needed for openai)")
 	fs.StringVar(&cfg.Token, "token", "", "authorization token (required for openai)")
@@ -186,3 +321,42 @@ func newEloCommand() *ffcli.Command {
 		},
 	}
 }
+
+func newEvalCommand() *ffcli.Command {
+	cmd := "eval"
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	_ = fs.String("config", "", "config file (optional)")
+
+	var cfg twai.EvalConfig
+	fs.BoolVar(&cfg.Debug, "debug", false, "debug mode")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 1, "number of concurrent requests")
+	fs.StringVar(&cfg.Input, "input", "", "gold file with tweets labelled with a human score in csv, json or jsonl format (stdin if empty)")
+	fs.StringVar(&cfg.Mode, "mode", "score", "evaluation mode (score or pair), score evaluates the score prompt and pair evaluates the elo judge")
+	fs.StringVar(&cfg.Prompt, "prompt", "", "prompt, a text/template with access to tweet fields (default score or elo prompt if empty)")
+	fs.StringVar(&cfg.PromptFile, "prompt-file", "", "file with the prompt template (overrides prompt)")
+	fs.BoolVar(&cfg.Structured, "structured", false, "ask for a json answer with the score of each criterion and a rationale (score mode)")
+	fs.StringVar(&cfg.Rubric, "rubric", "", "yaml rubric file with the criteria, weights and examples (score mode)")
+	fs.StringVar(&cfg.Examples, "examples", "", "file with tweets labelled with a human score, used as few-shot examples")
+	fs.StringVar(&cfg.Order, "order", "random", "tweet order in comparisons (fixed, random or both) (pair mode)")
+	fs.BoolVar(&cfg.LogProbs, "logprobs", false, "use the token probabilities of the answers")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", "", "directory of the cache of ai responses (user cache dir if empty)")
+	fs.BoolVar(&cfg.NoCache, "no-cache", false, "disable the cache of ai responses")
+	fs.StringVar(&cfg.Model, "model", "llama3", "ai model (llama3, gpt-3.5-turbo, etc)")
+	fs.StringVar(&cfg.Host, "host", "http://localhost:11434/v1", "ai endpoint host (not needed for openai)")
+	fs.StringVar(&cfg.Token, "token", "", "authorization token (required for openai)")
+
+	return &ffcli.Command{
+		Name:       cmd,
+		ShortUsage: fmt.Sprintf("twai %s [flags] <key> <value data...>", cmd),
+		Options: []ff.Option{
+			ff.WithConfigFileFlag("config"),
+			ff.WithConfigFileParser(ffyaml.Parser),
+			ff.WithEnvVarPrefix("twai"),
+		},
+		ShortHelp: fmt.Sprintf("twai %s command", cmd),
+		FlagSet:   fs,
+		Exec: func(ctx context.Context, args []string) error {
+			return twai.Eval(ctx, &cfg)
+		},
+	}
+}
@@ -0,0 +1,226 @@
+package twai
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/igolaizola/twai/pkg/openai"
+	"github.com/igolaizola/twai/pkg/rank"
+)
+
+// Ensemble methods
+const (
+	ensembleMean   = "mean"
+	ensembleMedian = "median"
+	ensembleVote   = "vote"
+)
+
+// scoreModel is one of the models used to score tweets.
+type scoreModel struct {
+	name   string
+	client *openai.Client
+}
+
+// newScoreModels creates the models of an ensemble from comma separated lists.
+func newScoreModels(cfg *ScoreConfig, cache *openai.Cache) ([]*scoreModel, error) {
+	configs, err := ensembleConfigs(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var ms []*scoreModel
+	seen := map[string]int{}
+	for _, c := range configs {
+		// Use unique names for the output columns
+		seen[c.Model]++
+		key := c.Model
+		if n := seen[c.Model]; n > 1 {
+			key = fmt.Sprintf("%s#%d", c.Model, n)
+		}
+		c.Cache = cache
+		ms = append(ms, &scoreModel{
+			name:   key,
+			client: openai.New(c),
+		})
+	}
+	return ms, nil
+}
+
+// ensembleConfigs returns the client configuration of each model of the
+// ensemble.
+// Hosts and tokens are matched with the models by position, a single host or
+// token is used for all the models, and missing or empty values default to the
+// host and token options.
+func ensembleConfigs(cfg *ScoreConfig) ([]*openai.Config, error) {
+	names := splitList(cfg.Models)
+	hostList := splitList(cfg.Hosts)
+	tokenList := splitList(cfg.Tokens)
+	pick := func(kind string, vs []string, i int, def string) (string, error) {
+		var v string
+		switch len(vs) {
+		case 0:
+		case 1:
+			v = vs[0]
+		case len(names):
+			v = vs[i]
+		default:
+			return "", fmt.Errorf("got %d %s for %d models", len(vs), kind, len(names))
+		}
+		if v == "" {
+			return def, nil
+		}
+		return v, nil
+	}
+
+	var configs []*openai.Config
+	for i, name := range names {
+		host, err := pick("hosts", hostList, i, cfg.Host)
+		if err != nil {
+			return nil, err
+		}
+		token, err := pick("tokens", tokenList, i, cfg.Token)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, &openai.Config{
+			Debug: cfg.Debug,
+			Model: name,
+			Host:  host,
+			Token: token,
+		})
+	}
+	return configs, nil
+}
+
+// splitList splits a comma separated list, empty values are kept so they can
+// be used as defaults.
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	vs := strings.Split(s, ",")
+	for i := range vs {
+		vs[i] = strings.TrimSpace(vs[i])
+	}
+	return vs
+}
+
+// combine combines the scores of several models using the ensemble method.
+func combine(method string, vs []float64) float64 {
+	switch method {
+	case ensembleMedian:
+		return median(vs)
+	case ensembleVote:
+		return vote(vs)
+	default:
+		return mean(vs)
+	}
+}
+
+func mean(vs []float64) float64 {
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
+
+func median(vs []float64) float64 {
+	sorted := append([]float64{}, vs...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// vote returns the most common rounded score, ties are resolved with the
+// score closest to the median and then with the lowest score.
+func vote(vs []float64) float64 {
+	counts := map[float64]int{}
+	var values []float64
+	for _, v := range vs {
+		r := math.Round(v)
+		if counts[r] == 0 {
+			values = append(values, r)
+		}
+		counts[r]++
+	}
+	// Iterate in order, so ties are resolved the same way every time
+	sort.Float64s(values)
+	m := median(vs)
+	var best float64
+	bestCount := 0
+	for _, v := range values {
+		n := counts[v]
+		if n > bestCount || (n == bestCount && math.Abs(v-m) < math.Abs(best-m)) {
+			best, bestCount = v, n
+		}
+	}
+	return best
+}
+
+// stddev returns the population standard deviation of the scores.
+func stddev(vs []float64) float64 {
+	m := mean(vs)
+	var sum float64
+	for _, v := range vs {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(vs)))
+}
+
+// reportDisagreement logs the agreement between each pair of models and the
+// tweets where the models disagree the most, so they can be reviewed by a
+// human.
+func reportDisagreement(models []*scoreModel, tws []*Tweet, threshold float64) {
+	for i, a := range models {
+		for _, b := range models[i+1:] {
+			var as, bs []float64
+			for _, tw := range tws {
+				va, okA := tw.Models[a.name]
+				vb, okB := tw.Models[b.name]
+				if okA && okB {
+					as = append(as, va)
+					bs = append(bs, vb)
+				}
+			}
+			if len(as) == 0 {
+				continue
+			}
+			log.Printf("agreement %s vs %s: spearman %.3f over %d tweets\n", a.name, b.name, rank.Spearman(as, bs), len(as))
+		}
+	}
+
+	var review []*Tweet
+	var sum float64
+	var n int
+	for _, tw := range tws {
+		if len(tw.Models) < 2 {
+			continue
+		}
+		sum += tw.Disagreement
+		n++
+		if tw.Disagreement >= threshold {
+			review = append(review, tw)
+		}
+	}
+	if n == 0 {
+		return
+	}
+	log.Printf("mean disagreement: %.2f\n", sum/float64(n))
+	if len(review) == 0 {
+		return
+	}
+	sort.SliceStable(review, func(i, j int) bool {
+		return review[i].Disagreement > review[j].Disagreement
+	})
+	log.Printf("%d tweets with disagreement of %.2f or more, review them:\n", len(review), threshold)
+	for _, tw := range review {
+		models, _ := tw.Models.MarshalCSV()
+		log.Printf("  %s disagreement %.2f %s\n", tw.Link, tw.Disagreement, models)
+	}
+}
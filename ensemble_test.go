@@ -0,0 +1,99 @@
+package twai
+
+import "testing"
+
+func TestEnsembleConfigs(t *testing.T) {
+	const host, token = "http://localhost:11434/v1", "sk-default"
+	tests := []struct {
+		name       string
+		models     string
+		hosts      string
+		tokens     string
+		wantHosts  []string
+		wantTokens []string
+		wantErr    bool
+	}{
+		{
+			name:       "defaults",
+			models:     "llama3,gpt-4o",
+			wantHosts:  []string{host, host},
+			wantTokens: []string{token, token},
+		},
+		{
+			name:       "single values",
+			models:     "llama3,gpt-4o",
+			hosts:      "http://ai:11434/v1",
+			tokens:     "sk-all",
+			wantHosts:  []string{"http://ai:11434/v1", "http://ai:11434/v1"},
+			wantTokens: []string{"sk-all", "sk-all"},
+		},
+		{
+			name:       "empty values",
+			models:     "llama3,gpt-4o",
+			hosts:      ",https://api.openai.com/v1",
+			tokens:     ",sk-openai",
+			wantHosts:  []string{host, "https://api.openai.com/v1"},
+			wantTokens: []string{token, "sk-openai"},
+		},
+		{
+			name:    "mismatched lists",
+			models:  "llama3,gpt-4o,mistral",
+			hosts:   "a,b",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs, err := ensembleConfigs(&ScoreConfig{
+				Host:   host,
+				Token:  token,
+				Models: tt.models,
+				Hosts:  tt.hosts,
+				Tokens: tt.tokens,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(configs) != len(tt.wantHosts) {
+				t.Fatalf("got %d configs, want %d", len(configs), len(tt.wantHosts))
+			}
+			for i, c := range configs {
+				if c.Host != tt.wantHosts[i] {
+					t.Errorf("model %d: got host %q, want %q", i, c.Host, tt.wantHosts[i])
+				}
+				if c.Token != tt.wantTokens[i] {
+					t.Errorf("model %d: got token %q, want %q", i, c.Token, tt.wantTokens[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVote(t *testing.T) {
+	tests := []struct {
+		vs   []float64
+		want float64
+	}{
+		{vs: []float64{7, 7, 3}, want: 7},
+		{vs: []float64{6.6, 7.2, 3}, want: 7},
+		{vs: []float64{2, 5, 6}, want: 5},
+		{vs: []float64{3, 7}, want: 3},
+		{vs: []float64{7, 3}, want: 3},
+		{vs: []float64{1, 4, 6, 9}, want: 4},
+		{vs: []float64{9, 6, 4, 1}, want: 4},
+	}
+	for _, tt := range tests {
+		// Run several times, map iteration order changes between runs
+		for i := 0; i < 20; i++ {
+			if got := vote(tt.vs); got != tt.want {
+				t.Fatalf("%v: got %v, want %v", tt.vs, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package twai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/igolaizola/twai/pkg/openai"
+	"github.com/igolaizola/twai/pkg/rank"
+)
+
+type EvalConfig struct {
+	Debug       bool
+	Concurrency int
+	Input       string
+	Mode        string
+	Prompt      string
+	PromptFile  string
+	Structured  bool
+	Rubric      string
+	Examples    string
+	Order       string
+	LogProbs    bool
+	CacheDir    string
+	NoCache     bool
+	Model       string
+	Host        string
+	Token       string
+}
+
+// Eval modes
+const (
+	// evalScore evaluates the score command against human scores
+	evalScore = "score"
+	// evalPair evaluates the elo judge against human-chosen pair winners
+	evalPair = "pair"
+)
+
+// Eval runs a scoring prompt or a pairwise judge over a file labelled by
+// humans and reports how well the model agrees with them.
+func Eval(ctx context.Context, cfg *EvalConfig) error {
+	log.Println("running")
+	defer log.Println("finished")
+
+	gold, err := readLabelled(cfg.Input)
+	if err != nil {
+		return err
+	}
+
+	cache, err := openCache(ctx, cfg.CacheDir, cfg.NoCache)
+	if err != nil {
+		return err
+	}
+	defer closeCache(cache)
+
+	// Concurrency settings
+	concurrency := cfg.Concurrency
+	if concurrency == 0 {
+		concurrency = 1
+	}
+
+	switch cfg.Mode {
+	case "", evalScore:
+		return evalScores(ctx, cfg, cache, gold, concurrency)
+	case evalPair:
+		return evalPairs(ctx, cfg, cache, gold, concurrency)
+	default:
+		return fmt.Errorf("invalid eval mode %q", cfg.Mode)
+	}
+}
+
+func evalScores(ctx context.Context, cfg *EvalConfig, cache *openai.Cache, gold []*labelled, concurrency int) error {
+	prompt := cfg.Prompt
+	if prompt == "" {
+		prompt = DefaultScorePrompt
+	}
+	sc, err := newScorer(&ScoreConfig{
+		Debug:      cfg.Debug,
+		Prompt:     prompt,
+		PromptFile: cfg.PromptFile,
+		Structured: cfg.Structured,
+		Rubric:     cfg.Rubric,
+		Examples:   cfg.Examples,
+		LogProbs:   cfg.LogProbs,
+		Model:      cfg.Model,
+		Host:       cfg.Host,
+		Token:      cfg.Token,
+	}, cache)
+	if err != nil {
+		return err
+	}
+
+	// Score the labelled tweets
+	preds := make([]*Tweet, len(gold))
+	var idx int
+	var lck sync.Mutex
+	concurrent(ctx, concurrency,
+		func() (int, bool) {
+			if idx >= len(gold) {
+				return 0, false
+			}
+			i := idx
+			log.Printf("ai: tweet %d/%d\n", idx+1, len(gold))
+			idx++
+			return i, true
+		},
+		func(i int) error {
+			tw, err := sc.score(ctx, &gold[i].Post)
+			if err != nil {
+				return err
+			}
+			lck.Lock()
+			defer lck.Unlock()
+			preds[i] = tw
+			return nil
+		},
+	)
+
+	// Compare the scores of the tweets that were scored
+	var want, got []float64
+	var accurate int
+	var absErr float64
+	confusion := newConfusion()
+	for i, p := range preds {
+		if p == nil {
+			continue
+		}
+		g := gold[i].Score
+		want = append(want, g)
+		got = append(got, p.Score)
+		if math.Round(g) == math.Round(p.Score) {
+			accurate++
+		}
+		absErr += math.Abs(g - p.Score)
+		confusion.add(formatScore(math.Round(g)), formatScore(math.Round(p.Score)))
+	}
+	if len(want) == 0 {
+		return fmt.Errorf("no tweets were scored")
+	}
+	n := float64(len(want))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "tweets\t%d/%d\n", len(want), len(gold))
+	fmt.Fprintf(w, "spearman\t%.3f\n", rank.Spearman(want, got))
+	fmt.Fprintf(w, "kendall\t%.3f\n", rank.KendallTau(want, got))
+	fmt.Fprintf(w, "accuracy\t%.1f%%\n", 100*float64(accurate)/n)
+	fmt.Fprintf(w, "mean absolute error\t%.3f\n", absErr/n)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nconfusion matrix (rows are human scores, columns are model scores):")
+	if err := confusion.write(os.Stdout); err != nil {
+		return err
+	}
+
+	// Agreement of each criterion labelled by humans
+	criteria := map[string]struct{}{}
+	for _, g := range gold {
+		for name := range g.Criteria {
+			criteria[name] = struct{}{}
+		}
+	}
+	if len(criteria) == 0 {
+		return nil
+	}
+	var names []string
+	for name := range criteria {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("\ncriteria:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "criterion\ttweets\tspearman\tkendall\tmean absolute error")
+	for _, name := range names {
+		var want, got []float64
+		var absErr float64
+		for i, p := range preds {
+			if p == nil {
+				continue
+			}
+			g, ok := gold[i].Criteria[name]
+			if !ok {
+				continue
+			}
+			v, ok := p.Criteria[name]
+			if !ok {
+				continue
+			}
+			want = append(want, g)
+			got = append(got, v)
+			absErr += math.Abs(g - v)
+		}
+		if len(want) == 0 {
+			fmt.Fprintf(w, "%s\t0\t-\t-\t-\n", name)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.3f\t%.3f\t%.3f\n", name, len(want),
+			rank.Spearman(want, got), rank.KendallTau(want, got), absErr/float64(len(want)))
+	}
+	return w.Flush()
+}
+
+func evalPairs(ctx context.Context, cfg *EvalConfig, cache *openai.Cache, gold []*labelled, concurrency int) error {
+	pairs, err := labelledPairs(gold)
+	if err != nil {
+		return err
+	}
+	prompt := cfg.Prompt
+	if prompt == "" {
+		prompt = DefaultEloPrompt
+	}
+	jdg, err := newJudge(&EloConfig{
+		Debug:      cfg.Debug,
+		Prompt:     prompt,
+		PromptFile: cfg.PromptFile,
+		Examples:   cfg.Examples,
+		Order:      cfg.Order,
+		LogProbs:   cfg.LogProbs,
+		Model:      cfg.Model,
+		Host:       cfg.Host,
+		Token:      cfg.Token,
+	}, cache)
+	if err != nil {
+		return err
+	}
+
+	// Judge the labelled pairs
+	preds := make([]*float64, len(pairs))
+	var idx int
+	var lck sync.Mutex
+	concurrent(ctx, concurrency,
+		func() (int, bool) {
+			if idx >= len(pairs) {
+				return 0, false
+			}
+			i := idx
+			log.Printf("ai: pair %d/%d\n", idx+1, len(pairs))
+			idx++
+			return i, true
+		},
+		func(i int) error {
+			score, err := jdg.compare(ctx, pairs[i].Tweet1, pairs[i].Tweet2)
+			if err != nil {
+				return err
+			}
+			lck.Lock()
+			defer lck.Unlock()
+			preds[i] = &score
+			return nil
+		},
+	)
+	jdg.report()
+
+	// Compare the winners of the pairs that were judged
+	var want, got []float64
+	var accurate int
+	confusion := newConfusion()
+	for i, p := range preds {
+		if p == nil {
+			continue
+		}
+		g := pairs[i].Score
+		want = append(want, g)
+		got = append(got, *p)
+		if winner(g) == winner(*p) {
+			accurate++
+		}
+		confusion.add(winner(g), winner(*p))
+	}
+	if len(want) == 0 {
+		return fmt.Errorf("no pairs were judged")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "pairs\t%d/%d\n", len(want), len(pairs))
+	fmt.Fprintf(w, "accuracy\t%.1f%%\n", 100*float64(accurate)/float64(len(want)))
+	fmt.Fprintf(w, "kendall\t%.3f\n", rank.KendallTau(want, got))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nconfusion matrix (rows are human winners, columns are model winners):")
+	return confusion.write(os.Stdout)
+}
+
+// winner returns the winner of a comparison with the score of tweet 1.
+func winner(score float64) string {
+	switch {
+	case score > 0.5:
+		return "1"
+	case score < 0.5:
+		return "2"
+	default:
+		return "tie"
+	}
+}
+
+func formatScore(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// confusion is a confusion matrix of human and model labels.
+type confusion struct {
+	counts map[[2]string]int
+	labels map[string]struct{}
+}
+
+func newConfusion() *confusion {
+	return &confusion{
+		counts: map[[2]string]int{},
+		labels: map[string]struct{}{},
+	}
+}
+
+func (c *confusion) add(want, got string) {
+	c.counts[[2]string{want, got}]++
+	c.labels[want] = struct{}{}
+	c.labels[got] = struct{}{}
+}
+
+// write writes the matrix as a table, numeric labels are sorted by value.
+func (c *confusion) write(out io.Writer) error {
+	var labels []string
+	for l := range c.labels {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		a, errA := strconv.ParseFloat(labels[i], 64)
+		b, errB := strconv.ParseFloat(labels[j], 64)
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return labels[i] < labels[j]
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "\t")
+	for _, l := range labels {
+		fmt.Fprintf(w, "%s\t", l)
+	}
+	fmt.Fprintln(w)
+	for _, want := range labels {
+		fmt.Fprintf(w, "%s\t", want)
+		for _, got := range labels {
+			fmt.Fprintf(w, "%d\t", c.counts[[2]string{want, got}])
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
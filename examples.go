@@ -0,0 +1,124 @@
+package twai
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+
+	"github.com/igolaizola/twai/pkg/openai"
+	"github.com/igolaizola/twai/pkg/twitter"
+)
+
+// labelled is a tweet labelled by a human, used as few-shot example.
+type labelled struct {
+	twitter.Post
+	// Score is the human score of the tweet
+	Score float64 `json:"score" csv:"score"`
+	// Criteria are the human scores of each criterion, used by the eval
+	// command
+	Criteria Scores `json:"criteria,omitempty" csv:"criteria"`
+	// Pair groups two tweets to compare, the tweet with the highest score is
+	// the winner and equal scores are a tie
+	Pair string `json:"pair,omitempty" csv:"pair"`
+}
+
+// labelledPair is a pair of tweets with the human outcome.
+type labelledPair struct {
+	pair
+	// Score is the score of tweet 1
+	Score float64
+}
+
+// readLabelled reads the labelled tweets from a file.
+func readLabelled(path string) ([]*labelled, error) {
+	ls, err := readItems[*labelled](path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read examples: %w", err)
+	}
+	if len(ls) == 0 {
+		return nil, fmt.Errorf("examples file %s is empty", path)
+	}
+	return ls, nil
+}
+
+// labelledPairs groups the labelled tweets by pair id.
+// If no pair ids are set, consecutive tweets are paired.
+func labelledPairs(ls []*labelled) ([]*labelledPair, error) {
+	var groups [][]*labelled
+	index := map[string]int{}
+	for i, l := range ls {
+		key := l.Pair
+		if key == "" {
+			key = strconv.Itoa(i / 2)
+		}
+		n, ok := index[key]
+		if !ok {
+			n = len(groups)
+			index[key] = n
+			groups = append(groups, nil)
+		}
+		groups[n] = append(groups[n], l)
+	}
+	var pairs []*labelledPair
+	for _, g := range groups {
+		if len(g) != 2 {
+			return nil, fmt.Errorf("example pair %q has %d tweets instead of 2", g[0].Pair, len(g))
+		}
+		score := 0.5
+		switch {
+		case g[0].Score > g[1].Score:
+			score = 1
+		case g[0].Score < g[1].Score:
+			score = 0
+		}
+		pairs = append(pairs, &labelledPair{
+			pair:  pair{Tweet1: &g[0].Post, Tweet2: &g[1].Post},
+			Score: score,
+		})
+	}
+	return pairs, nil
+}
+
+// outcomeAnswer returns the answer to a comparison with the score of tweet 1.
+func outcomeAnswer(score float64) string {
+	switch {
+	case score == 0.5:
+		return "tie"
+	case score >= 1:
+		return "1"
+	case score <= 0:
+		return "2"
+	case score > 0.5:
+		return "1 slightly better"
+	default:
+		return "2 slightly better"
+	}
+}
+
+// withShots returns the few-shot examples followed by the message.
+func withShots(shots []openai.Message, msg string) []openai.Message {
+	msgs := make([]openai.Message, 0, len(shots)+1)
+	msgs = append(msgs, shots...)
+	return append(msgs, openai.Message{Role: openai.RoleUser, Content: msg})
+}
+
+// shot returns the question and the answer of a few-shot example.
+func shot(question, answer string) []openai.Message {
+	return []openai.Message{
+		{Role: openai.RoleUser, Content: question},
+		{Role: openai.RoleAssistant, Content: answer},
+	}
+}
+
+// shotsKey returns a key that identifies the few-shot examples, so scores
+// obtained with different examples aren't mixed.
+func shotsKey(shots []openai.Message) string {
+	if len(shots) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, m := range shots {
+		fmt.Fprintf(h, "%s\x00%s\x00", m.Role, m.Content)
+	}
+	return fmt.Sprintf("\n\nexamples: %x", h.Sum(nil)[:8])
+}
@@ -0,0 +1,257 @@
+package twai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+)
+
+// File formats
+const (
+	formatCSV   = "csv"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+)
+
+// output writes items to a file or to stdout.
+// JSONL items are streamed as they are produced, CSV and JSON items are
+// written when the output is closed.
+type output[T any] struct {
+	path   string
+	format string
+	w      io.Writer
+	file   *os.File
+	enc    *json.Encoder
+}
+
+// newOutput creates an output for the given path and format.
+// If the path is empty, items are written to stdout unless quiet is set.
+// If the format is empty, it is obtained from the path extension.
+func newOutput[T any](path, format string, quiet bool) (*output[T], error) {
+	format = strings.ToLower(format)
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			format = formatJSON
+		case ".jsonl":
+			format = formatJSONL
+		default:
+			format = formatCSV
+		}
+	}
+	switch format {
+	case formatCSV, formatJSON, formatJSONL:
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	o := &output[T]{
+		path:   path,
+		format: format,
+		w:      os.Stdout,
+	}
+	if path == "" && quiet {
+		o.w = io.Discard
+	}
+	// Open the file now to stream JSONL items
+	if format == formatJSONL {
+		if path != "" {
+			f, err := os.Create(path)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't create output file: %w", err)
+			}
+			o.file = f
+			o.w = f
+		}
+		o.enc = json.NewEncoder(o.w)
+	}
+	return o, nil
+}
+
+// stream writes an item if the format supports streaming.
+func (o *output[T]) stream(v T) error {
+	if o.enc == nil {
+		return nil
+	}
+	if err := o.enc.Encode(v); err != nil {
+		return fmt.Errorf("couldn't write item: %w", err)
+	}
+	return nil
+}
+
+// close writes the items if the format doesn't support streaming and closes
+// the output.
+func (o *output[T]) close(vs []T) error {
+	var data []byte
+	var err error
+	switch o.format {
+	case formatJSONL:
+		if o.file == nil {
+			return nil
+		}
+		if err := o.file.Close(); err != nil {
+			return fmt.Errorf("couldn't close output file: %w", err)
+		}
+		fmt.Println("created file:", o.path)
+		return nil
+	case formatJSON:
+		if vs == nil {
+			vs = []T{}
+		}
+		data, err = json.MarshalIndent(vs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("couldn't marshal items to json: %w", err)
+		}
+		data = append(data, '\n')
+	default:
+		data, err = gocsv.MarshalBytes(&vs)
+		if err != nil {
+			return fmt.Errorf("couldn't marshal items to csv: %w", err)
+		}
+		data, err = appendColumns(data, vs)
+		if err != nil {
+			return err
+		}
+	}
+	// Write to file if output is provided
+	if o.path != "" {
+		if err := os.WriteFile(o.path, data, 0644); err != nil {
+			return fmt.Errorf("couldn't write items to file: %w", err)
+		}
+		fmt.Println("created file:", o.path)
+		return nil
+	}
+	if _, err := o.w.Write(data); err != nil {
+		return fmt.Errorf("couldn't write items: %w", err)
+	}
+	return nil
+}
+
+// column is a CSV column of an item that isn't a field, like the score of
+// each criterion.
+type column struct {
+	name  string
+	value string
+}
+
+// columner is implemented by items with extra CSV columns.
+type columner interface {
+	columns() []column
+}
+
+// appendColumns appends the extra columns of the items to the CSV data.
+// Columns are added in the order they are found, items without a column get
+// an empty value.
+func appendColumns[T any](data []byte, vs []T) ([]byte, error) {
+	var names []string
+	index := map[string]int{}
+	rows := make([][]column, len(vs))
+	for i, v := range vs {
+		c, ok := any(v).(columner)
+		if !ok {
+			return data, nil
+		}
+		rows[i] = c.columns()
+		for _, col := range rows[i] {
+			if _, ok := index[col.name]; !ok {
+				index[col.name] = len(names)
+				names = append(names, col.name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return data, nil
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read csv: %w", err)
+	}
+	if len(records) != len(vs)+1 {
+		return nil, fmt.Errorf("csv has %d records instead of %d", len(records), len(vs)+1)
+	}
+	records[0] = append(records[0], names...)
+	for i, row := range rows {
+		values := make([]string, len(names))
+		for _, col := range row {
+			values[index[col.name]] = col.value
+		}
+		records[i+1] = append(records[i+1], values...)
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("couldn't write csv: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readItems reads the items from a file or from stdin if the path is empty
+// or "-".
+// The format is detected from the content.
+func readItems[T any](path string) ([]T, error) {
+	var r io.Reader
+	if path == "" || path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open input file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read input: %w", err)
+	}
+
+	var vs []T
+	switch detectFormat(data) {
+	case formatJSON:
+		if err := json.Unmarshal(data, &vs); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal json input: %w", err)
+		}
+	case formatJSONL:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var v T
+			if err := json.Unmarshal(line, &v); err != nil {
+				return nil, fmt.Errorf("couldn't unmarshal jsonl input: %w", err)
+			}
+			vs = append(vs, v)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("couldn't read jsonl input: %w", err)
+		}
+	default:
+		if err := gocsv.UnmarshalBytes(data, &vs); err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal csv input: %w", err)
+		}
+	}
+	return vs, nil
+}
+
+// detectFormat detects the format of the data by its first character.
+func detectFormat(data []byte) string {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		return formatJSON
+	case bytes.HasPrefix(data, []byte("{")):
+		return formatJSONL
+	default:
+		return formatCSV
+	}
+}
@@ -0,0 +1,94 @@
+package twai
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/igolaizola/twai/pkg/twitter"
+)
+
+func TestOutputColumns(t *testing.T) {
+	post := &twitter.Post{ID: "1", UserID: "golang", Text: "Go 1.22 is released"}
+	tests := []struct {
+		name   string
+		tweets []*Tweet
+		// want are the extra columns of the header and each row
+		want [][]string
+	}{
+		{
+			name:   "no extra columns",
+			tweets: []*Tweet{newTweet(post, 8), newTweet(post, 6)},
+			want:   [][]string{nil, nil, nil},
+		},
+		{
+			name: "criteria",
+			tweets: []*Tweet{
+				{Score: 8, Criteria: Scores{"relevance": 9, "clarity": 7.5}, post: post},
+				{Score: 6, Criteria: Scores{"clarity": 6, "impact": 5}, post: post},
+				{Score: 5, post: post},
+			},
+			want: [][]string{
+				{"clarity", "relevance", "impact"},
+				{"7.5", "9", ""},
+				{"6", "", "5"},
+				{"", "", ""},
+			},
+		},
+		{
+			name: "models",
+			tweets: []*Tweet{
+				{Score: 7, Models: Scores{"llama3": 8, "gpt-4o": 6}, Disagreement: 1, post: post},
+				{Score: 5, Criteria: Scores{"clarity": 5}, Models: Scores{"llama3": 5}, post: post},
+			},
+			want: [][]string{
+				{"score_gpt-4o", "score_llama3", "clarity"},
+				{"6", "8", ""},
+				{"", "5", "5"},
+			},
+		},
+	}
+	fixed := len(writeCSV(t, nil)[0])
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := writeCSV(t, tt.tweets)
+			if len(records) != len(tt.want) {
+				t.Fatalf("got %d records, want %d", len(records), len(tt.want))
+			}
+			for i, record := range records {
+				var got []string
+				if len(record) > fixed {
+					got = record[fixed:]
+				}
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("record %d: got %q, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// writeCSV writes the tweets to a CSV file and returns its records.
+func writeCSV(t *testing.T, tweets []*Tweet) [][]string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tweets.csv")
+	out, err := newOutput[*Tweet](path, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.close(tweets); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
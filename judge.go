@@ -0,0 +1,234 @@
+package twai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+
+	"github.com/igolaizola/twai/pkg/openai"
+	"github.com/igolaizola/twai/pkg/twitter"
+)
+
+// Comparison orders
+const (
+	// orderFixed keeps the order of the pair chosen by the pairing strategy
+	orderFixed = "fixed"
+	// orderRandom randomizes the position of the tweets
+	orderRandom = "random"
+	// orderBoth asks in both orders and treats inconsistent answers as a draw
+	orderBoth = "both"
+)
+
+// DefaultEloPrompt is the default prompt of the elo command
+const DefaultEloPrompt = "Which tweet is best based on relevance, clarity, engagement, and impact? 1 or 2? Answer only with the number 1 or 2, or with tie if both are equally good or bad."
+
+// pairPromptSuffix is appended to plain elo prompts
+const pairPromptSuffix = `
+
+TWEET 1 (by @{{.Tweet1.UserID}}, {{.Tweet1.Likes}} likes, {{.Tweet1.Retweets}} retweets, {{.Tweet1.Views}} views):
+{{.Tweet1.Text}}
+
+TWEET 2 (by @{{.Tweet2.UserID}}, {{.Tweet2.Likes}} likes, {{.Tweet2.Retweets}} retweets, {{.Tweet2.Views}} views):
+{{.Tweet2.Text}}`
+
+// pair is the data used to render elo prompts.
+type pair struct {
+	Tweet1 *twitter.Post
+	Tweet2 *twitter.Post
+}
+
+// judge asks the model which tweet of a pair is best.
+type judge struct {
+	client   *openai.Client
+	prompt   *prompt
+	order    string
+	logprobs bool
+	shots    []openai.Message
+	// key identifies the prompt and the examples
+	key string
+
+	// Count how often the model picks each tweet position
+	lck          sync.Mutex
+	picks        [2]int
+	ties         int
+	doubles      int
+	inconsistent int
+}
+
+func newJudge(cfg *EloConfig, cache *openai.Cache) (*judge, error) {
+	order := cfg.Order
+	switch order {
+	case "":
+		order = orderRandom
+	case orderFixed, orderRandom, orderBoth:
+	default:
+		return nil, fmt.Errorf("invalid order %q", order)
+	}
+
+	// Prompt template, the tweets are appended to plain prompts
+	prompt, err := newPrompt(cfg.Prompt, cfg.PromptFile, pairPromptSuffix, &pair{Tweet1: &twitter.Post{}, Tweet2: &twitter.Post{}})
+	if err != nil {
+		return nil, err
+	}
+
+	j := &judge{
+		client: openai.New(&openai.Config{
+			Debug: cfg.Debug,
+			Model: cfg.Model,
+			Host:  cfg.Host,
+			Token: cfg.Token,
+			Cache: cache,
+		}),
+		prompt:   prompt,
+		order:    order,
+		logprobs: cfg.LogProbs,
+	}
+
+	// Few-shot examples labelled by humans
+	if cfg.Examples != "" {
+		ls, err := readLabelled(cfg.Examples)
+		if err != nil {
+			return nil, err
+		}
+		pairs, err := labelledPairs(ls)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pairs {
+			question, err := prompt.render(&p.pair)
+			if err != nil {
+				return nil, err
+			}
+			j.shots = append(j.shots, shot(question, outcomeAnswer(p.Score))...)
+		}
+		log.Printf("using %d example pairs from %s\n", len(pairs), cfg.Examples)
+	}
+	j.key = prompt.raw + shotsKey(j.shots)
+	if j.logprobs {
+		j.key += logprobsKey
+	}
+	return j, nil
+}
+
+// ask asks the model which tweet is best and returns the score of the first
+// tweet.
+func (j *judge) ask(ctx context.Context, a, b *twitter.Post) (float64, error) {
+	msg, err := j.prompt.render(&pair{Tweet1: a, Tweet2: b})
+	if err != nil {
+		return 0, err
+	}
+	score, err := j.outcome(ctx, withShots(j.shots, msg))
+	if err != nil {
+		return 0, err
+	}
+	j.lck.Lock()
+	defer j.lck.Unlock()
+	switch {
+	case score > 0.5:
+		j.picks[0]++
+	case score < 0.5:
+		j.picks[1]++
+	default:
+		j.ties++
+	}
+	return score, nil
+}
+
+// outcome asks the model and returns the score of the first tweet.
+// Using log probabilities, the score is the probability of the first tweet
+// winning, so it is a soft outcome.
+func (j *judge) outcome(ctx context.Context, msgs []openai.Message) (float64, error) {
+	// Answers are parsed before they are cached, so answers that can't be
+	// parsed are asked again
+	var score float64
+	parse := func(resp string) error {
+		var err error
+		score, err = parseOutcome(resp)
+		if err != nil {
+			log.Println(resp)
+		}
+		return err
+	}
+	if j.logprobs {
+		if _, _, err := j.client.ChatProbs(ctx, msgs, func(resp string, probs map[string]float64) error {
+			var ok bool
+			if score, _, ok = probsOutcome(probs); ok {
+				return nil
+			}
+			log.Println("no outcome probabilities in response, using the answer")
+			return parse(resp)
+		}); err != nil {
+			return 0, err
+		}
+		return score, nil
+	}
+	if _, err := j.client.Chat(ctx, msgs, parse); err != nil {
+		return 0, err
+	}
+	return score, nil
+}
+
+// bothOrders returns the score of a from the scores of asking in both orders,
+// a first and b first, and whether the answers are inconsistent.
+// Consistent answers are averaged and inconsistent answers are a draw.
+func bothOrders(first, second float64) (float64, bool) {
+	swapped := 1.0 - second
+	if (first-0.5)*(swapped-0.5) < 0 {
+		return 0.5, true
+	}
+	return (first + swapped) / 2, false
+}
+
+// compare compares the tweets using the configured order and returns the
+// score of a.
+func (j *judge) compare(ctx context.Context, a, b *twitter.Post) (float64, error) {
+	switch j.order {
+	case orderBoth:
+		first, err := j.ask(ctx, a, b)
+		if err != nil {
+			return 0, err
+		}
+		second, err := j.ask(ctx, b, a)
+		if err != nil {
+			return 0, err
+		}
+		score, inconsistent := bothOrders(first, second)
+		j.lck.Lock()
+		j.doubles++
+		if inconsistent {
+			j.inconsistent++
+		}
+		j.lck.Unlock()
+		return score, nil
+	case orderRandom:
+		if rand.Intn(2) == 0 {
+			return j.ask(ctx, a, b)
+		}
+		score, err := j.ask(ctx, b, a)
+		if err != nil {
+			return 0, err
+		}
+		return 1.0 - score, nil
+	default:
+		return j.ask(ctx, a, b)
+	}
+}
+
+// report logs how often the model picks each tweet position, a rate far from
+// 50% means the model is biased towards a position instead of judging the
+// content.
+func (j *judge) report() {
+	j.lck.Lock()
+	defer j.lck.Unlock()
+	if total := j.picks[0] + j.picks[1]; total > 0 {
+		log.Printf("tweet 1 picked %d/%d times (%.1f%%)\n", j.picks[0], total, 100*float64(j.picks[0])/float64(total))
+	}
+	if j.ties > 0 {
+		log.Printf("%d answers were a tie\n", j.ties)
+	}
+	if j.doubles > 0 {
+		log.Printf("position bias: %d/%d comparisons changed the answer when swapping the order (%.1f%%)\n", j.inconsistent, j.doubles, 100*float64(j.inconsistent)/float64(j.doubles))
+	}
+}
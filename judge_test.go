@@ -0,0 +1,32 @@
+package twai
+
+import "testing"
+
+func TestBothOrders(t *testing.T) {
+	tests := []struct {
+		name             string
+		first, second    float64
+		want             float64
+		wantInconsistent bool
+	}{
+		{name: "consistent win", first: 1, second: 0, want: 1},
+		{name: "consistent loss", first: 0, second: 1, want: 0},
+		{name: "graded win", first: 0.75, second: 0, want: 0.875},
+		{name: "tie and win", first: 0.5, second: 0, want: 0.75},
+		{name: "ties", first: 0.5, second: 0.5, want: 0.5},
+		{name: "position bias", first: 1, second: 1, want: 0.5, wantInconsistent: true},
+		{name: "graded position bias", first: 0.75, second: 1, want: 0.5, wantInconsistent: true},
+		{name: "soft position bias", first: 0.6, second: 0.7, want: 0.5, wantInconsistent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, inconsistent := bothOrders(tt.first, tt.second)
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if inconsistent != tt.wantInconsistent {
+				t.Errorf("got inconsistent %v, want %v", inconsistent, tt.wantInconsistent)
+			}
+		})
+	}
+}
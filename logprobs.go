@@ -0,0 +1,49 @@
+package twai
+
+import (
+	"strconv"
+)
+
+// logprobsKey is added to the prompt key, so scores obtained from log
+// probabilities aren't mixed with sampled answers
+const logprobsKey = "\n\nlogprobs"
+
+// probsScore returns the expected score using the probabilities of the
+// numeric answers within the scale, and the confidence, which is the
+// probability of the most likely answer.
+// It returns false if there are no numeric answers within the scale.
+func probsScore(probs map[string]float64, scale rubricScale) (float64, float64, bool) {
+	var total, sum, best float64
+	for token, p := range probs {
+		n, err := strconv.Atoi(token)
+		if err != nil {
+			continue
+		}
+		if float64(n) < scale.Min || float64(n) > scale.Max {
+			continue
+		}
+		total += p
+		sum += p * float64(n)
+		if p > best {
+			best = p
+		}
+	}
+	if total == 0 {
+		return 0, 0, false
+	}
+	return sum / total, best / total, true
+}
+
+// probsOutcome returns the score of tweet 1 using the probabilities of
+// answering 1, 2 or tie, and the confidence, which is the probability of the
+// most likely answer.
+// It returns false if none of the answers is found.
+func probsOutcome(probs map[string]float64) (float64, float64, bool) {
+	p1, p2 := probs["1"], probs["2"]
+	tie := probs["tie"] + probs["draw"]
+	total := p1 + p2 + tie
+	if total == 0 {
+		return 0, 0, false
+	}
+	return (p1 + tie/2) / total, max(p1, p2, tie) / total, true
+}
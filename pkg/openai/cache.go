@@ -0,0 +1,113 @@
+package openai
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	_ "modernc.org/sqlite"
+)
+
+// Cache is a persistent cache of chat completion responses, keyed by a hash
+// of the host and the request, which includes the model, the messages and the
+// generation parameters.
+type Cache struct {
+	db     *sql.DB
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+const cacheSchema = `
+CREATE TABLE IF NOT EXISTS responses (
+	key TEXT PRIMARY KEY,
+	response TEXT NOT NULL,
+	time TIMESTAMP NOT NULL
+);
+`
+
+// OpenCache opens the cache stored in the given directory, creating it if
+// needed.
+func OpenCache(ctx context.Context, dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("openai: couldn't create cache dir: %w", err)
+	}
+	path := filepath.Join(dir, "cache.db")
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return nil, fmt.Errorf("openai: couldn't open cache: %w", err)
+	}
+	// SQLite doesn't support concurrent writes
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, cacheSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("openai: couldn't create cache schema: %w", err)
+	}
+	return &Cache{db: db}, nil
+}
+
+// Close closes the cache.
+func (c *Cache) Close() error {
+	return c.db.Close()
+}
+
+// Stats returns the number of cache hits and misses.
+func (c *Cache) Stats() (int64, int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// cacheKey returns the key of a request sent to a host.
+func cacheKey(host string, req openai.ChatCompletionRequest) (string, error) {
+	js, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("openai: couldn't marshal request: %w", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(host))
+	h.Write([]byte{0})
+	h.Write(js)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// get returns the cached response of the key.
+func (c *Cache) get(ctx context.Context, key string) (*openai.ChatCompletionResponse, bool, error) {
+	var data string
+	err := c.db.QueryRowContext(ctx, `SELECT response FROM responses WHERE key = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("openai: couldn't query cache: %w", err)
+	}
+	var resp openai.ChatCompletionResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, false, fmt.Errorf("openai: couldn't unmarshal cached response: %w", err)
+	}
+	return &resp, true, nil
+}
+
+// put stores the response of the key.
+func (c *Cache) put(ctx context.Context, key string, resp *openai.ChatCompletionResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("openai: couldn't marshal response: %w", err)
+	}
+	if _, err := c.db.ExecContext(ctx, `
+		INSERT INTO responses (key, response, time) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			response = excluded.response,
+			time = excluded.time`,
+		key, string(data), time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("openai: couldn't store response in cache: %w", err)
+	}
+	return nil
+}
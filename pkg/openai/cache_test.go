@@ -0,0 +1,130 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	cache := openCache(t)
+
+	req := openai.ChatCompletionRequest{
+		Model:    "llama3",
+		Messages: []openai.ChatCompletionMessage{{Role: RoleUser, Content: "Rate this tweet"}},
+	}
+	key, err := cacheKey("http://localhost:11434/v1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := cache.get(ctx, key); err != nil || ok {
+		t.Fatalf("got ok %v and error %v, want a miss", ok, err)
+	}
+
+	want := &openai.ChatCompletionResponse{
+		ID:      "chatcmpl-1",
+		Model:   "llama3",
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: RoleAssistant, Content: "8"}}},
+	}
+	if err := cache.put(ctx, key, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := cache.get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected a hit")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// The key depends on the host and the request
+	other, err := cacheKey("https://api.openai.com/v1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == key {
+		t.Error("same key for different hosts")
+	}
+	if _, ok, _ := cache.get(ctx, other); ok {
+		t.Error("expected a miss for a different host")
+	}
+}
+
+func TestCacheAccept(t *testing.T) {
+	ctx := context.Background()
+	cache := openCache(t)
+
+	// The server answers "maybe" the first time and "8" afterwards
+	answers := []string{"maybe", "8"}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		answer := answers[min(requests, len(answers)-1)]
+		requests++
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			ID:      "chatcmpl-1",
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: RoleAssistant, Content: answer}}},
+		})
+	}))
+	defer server.Close()
+	client := New(&Config{Model: "llama3", Host: server.URL, Cache: cache})
+
+	errNumber := errors.New("not a number")
+	check := func(answer string) error {
+		if answer != "8" {
+			return errNumber
+		}
+		return nil
+	}
+	msgs := []Message{{Role: RoleUser, Content: "Rate this tweet"}}
+
+	tests := []struct {
+		name         string
+		want         string
+		wantErr      error
+		wantRequests int
+		wantHits     int64
+		wantMisses   int64
+	}{
+		// The rejected answer isn't cached, so it is asked again
+		{name: "rejected", wantErr: errNumber, wantRequests: 1, wantHits: 0, wantMisses: 1},
+		{name: "accepted", want: "8", wantRequests: 2, wantHits: 0, wantMisses: 2},
+		{name: "cached", want: "8", wantRequests: 2, wantHits: 1, wantMisses: 2},
+	}
+	for _, tt := range tests {
+		got, err := client.Chat(ctx, msgs, check)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: got error %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if requests != tt.wantRequests {
+			t.Errorf("%s: got %d requests, want %d", tt.name, requests, tt.wantRequests)
+		}
+		hits, misses := cache.Stats()
+		if hits != tt.wantHits || misses != tt.wantMisses {
+			t.Errorf("%s: got %d hits and %d misses, want %d and %d", tt.name, hits, misses, tt.wantHits, tt.wantMisses)
+		}
+	}
+}
+
+// openCache opens a cache in a temporary directory.
+func openCache(t *testing.T) *Cache {
+	t.Helper()
+	cache, err := OpenCache(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	return cache
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"reflect"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
 )
@@ -13,6 +16,8 @@ type Client struct {
 	debug  bool
 	client *openai.Client
 	model  string
+	host   string
+	cache  *Cache
 }
 
 type Config struct {
@@ -20,6 +25,8 @@ type Config struct {
 	Token string
 	Host  string
 	Model string
+	// Cache stores the responses to reuse them, it's disabled if nil
+	Cache *Cache
 }
 
 func New(cfg *Config) *Client {
@@ -39,39 +46,202 @@ func New(cfg *Config) *Client {
 		debug:  cfg.Debug,
 		client: client,
 		model:  model,
+		host:   cfg.Host,
+		cache:  cfg.Cache,
 	}
 }
 
+// Message roles
+const (
+	RoleSystem    = openai.ChatMessageRoleSystem
+	RoleUser      = openai.ChatMessageRoleUser
+	RoleAssistant = openai.ChatMessageRoleAssistant
+)
+
+// Message is a chat message.
+type Message struct {
+	Role    string
+	Content string
+}
+
 func (c *Client) ChatCompletion(ctx context.Context, msg string) (string, error) {
-	req := openai.ChatCompletionRequest{
-		Model: c.model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: msg,
-			},
-		},
+	return c.Chat(ctx, []Message{{Role: RoleUser, Content: msg}}, nil)
+}
+
+// Chat sends the messages, e.g. few-shot examples followed by the question,
+// and returns the answer.
+// The answer is only cached if check, when not nil, accepts it, otherwise the
+// error of check is returned, so the question is asked again the next time.
+func (c *Client) Chat(ctx context.Context, msgs []Message, check func(answer string) error) (string, error) {
+	resp, err := c.create(ctx, c.request(msgs), func(resp *openai.ChatCompletionResponse) error {
+		if check == nil {
+			return nil
+		}
+		return check(resp.Choices[0].Message.Content)
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// JSONCompletion asks for a JSON object response and unmarshals it into v.
+func (c *Client) JSONCompletion(ctx context.Context, msg string, v any) error {
+	return c.JSONChat(ctx, []Message{{Role: RoleUser, Content: msg}}, v, nil)
+}
+
+// JSONChat sends the messages asking for a JSON object response and
+// unmarshals it into v.
+// The answer is only cached if it can be unmarshaled and check, when not nil,
+// accepts v.
+func (c *Client) JSONChat(ctx context.Context, msgs []Message, v any, check func() error) error {
+	req := c.request(msgs)
+	req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+	}
+	_, err := c.create(ctx, req, func(resp *openai.ChatCompletionResponse) error {
+		// Some models wrap the JSON object in a markdown code block
+		content := strings.TrimSpace(resp.Choices[0].Message.Content)
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(content, "```")
+		// Reset v, so a cached response that isn't accepted doesn't leave
+		// fields behind
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			rv.Elem().SetZero()
+		}
+		if err := json.Unmarshal([]byte(content), v); err != nil {
+			return fmt.Errorf("openai: couldn't unmarshal json response %q: %w", content, err)
+		}
+		if check == nil {
+			return nil
+		}
+		return check()
+	})
+	return err
+}
+
+func (c *Client) request(msgs []Message) openai.ChatCompletionRequest {
+	var messages []openai.ChatCompletionMessage
+	for _, m := range msgs {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    m.Role,
+			Content: m.Content,
+		})
+	}
+	return openai.ChatCompletionRequest{
+		Model:     c.model,
+		Messages:  messages,
 		MaxTokens: 1024,
 	}
+}
+
+// TopTokens is the number of alternatives returned for each token when asking
+// for log probabilities
+const TopTokens = 20
+
+// ChatProbs sends the messages asking for log probabilities and returns the
+// answer with the probability of the alternatives of its first token.
+// Alternatives are trimmed and lowercased, so " Tie" and "tie" are merged.
+// The probabilities are empty if the endpoint doesn't support log
+// probabilities.
+// The answer is only cached if check, when not nil, accepts it.
+func (c *Client) ChatProbs(ctx context.Context, msgs []Message, check func(answer string, probs map[string]float64) error) (string, map[string]float64, error) {
+	req := c.request(msgs)
+	req.LogProbs = true
+	req.TopLogProbs = TopTokens
+	resp, err := c.create(ctx, req, func(resp *openai.ChatCompletionResponse) error {
+		if check == nil {
+			return nil
+		}
+		answer, probs := topProbs(resp)
+		return check(answer, probs)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	answer, probs := topProbs(resp)
+	return answer, probs, nil
+}
+
+// topProbs returns the answer and the probability of the alternatives of its
+// first token.
+func topProbs(resp *openai.ChatCompletionResponse) (string, map[string]float64) {
+	choice := resp.Choices[0]
+	probs := map[string]float64{}
+	if choice.LogProbs == nil {
+		return choice.Message.Content, probs
+	}
+	for _, lp := range choice.LogProbs.Content {
+		// Skip leading whitespace or punctuation tokens
+		if strings.TrimSpace(strings.Trim(lp.Token, `"'*`)) == "" {
+			continue
+		}
+		for _, top := range lp.TopLogProbs {
+			key := strings.ToLower(strings.TrimSpace(top.Token))
+			probs[key] += math.Exp(top.LogProb)
+		}
+		break
+	}
+	return choice.Message.Content, probs
+}
+
+// create sends the request and returns the response.
+// Responses are only cached if accept doesn't return an error, cached
+// responses that aren't accepted are requested again.
+func (c *Client) create(ctx context.Context, req openai.ChatCompletionRequest, accept func(*openai.ChatCompletionResponse) error) (*openai.ChatCompletionResponse, error) {
 	if c.debug {
 		js, _ := json.MarshalIndent(req, "", "  ")
 		log.Println("openai: req:", string(js))
 	}
 
+	// Reuse the cached response if available
+	var key string
+	if c.cache != nil {
+		var err error
+		key, err = cacheKey(c.host, req)
+		if err != nil {
+			return nil, err
+		}
+		cached, ok, err := c.cache.get(ctx, key)
+		if err != nil {
+			log.Println(err)
+		}
+		if ok {
+			err := accept(cached)
+			if err == nil {
+				c.cache.hits.Add(1)
+				if c.debug {
+					log.Println("openai: cache hit:", key)
+				}
+				return cached, nil
+			}
+			log.Printf("openai: cached response not accepted, requesting it again: %v\n", err)
+		}
+		c.cache.misses.Add(1)
+	}
+
 	resp, err := c.client.CreateChatCompletion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("openai: couldn't create chat completion: %w", err)
+		return nil, fmt.Errorf("openai: couldn't create chat completion: %w", err)
 	}
 	if c.debug {
 		js, _ := json.MarshalIndent(resp, "", "  ")
 		log.Println("openai: resp:", string(js))
 	}
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("openai: chat completion response is empty")
+		return nil, fmt.Errorf("openai: chat completion response is empty")
+	}
+	if resp.Choices[0].Message.Content == "" {
+		return nil, fmt.Errorf("openai: chat completion response is empty")
+	}
+	if err := accept(&resp); err != nil {
+		return nil, err
 	}
-	content := resp.Choices[0].Message.Content
-	if content == "" {
-		return "", fmt.Errorf("openai: chat completion response is empty")
+	if c.cache != nil {
+		if err := c.cache.put(ctx, key, &resp); err != nil {
+			log.Println(err)
+		}
 	}
-	return content, nil
+	return &resp, nil
 }
@@ -0,0 +1,78 @@
+package rank
+
+import "math"
+
+// bradleyTerry is a Bradley-Terry model fitted by maximum likelihood using
+// all the comparisons, so the result doesn't depend on their order.
+// See Hunter (2004), MM algorithms for generalized Bradley-Terry models.
+type bradleyTerry struct {
+	n     int
+	wins  []float64
+	games games
+}
+
+// Fit parameters
+const (
+	bradleyTerryPrior     = 1.0
+	bradleyTerryMaxIters  = 1000
+	bradleyTerryTolerance = 1e-9
+)
+
+func newBradleyTerry(n int) *bradleyTerry {
+	return &bradleyTerry{
+		n:     n,
+		wins:  make([]float64, n),
+		games: games{},
+	}
+}
+
+// Update implements Ranker.
+func (b *bradleyTerry) Update(i, j int, score float64) {
+	b.wins[i] += score
+	b.wins[j] += 1 - score
+	b.games.add(i, j)
+}
+
+// Ratings implements Ranker.
+// Ratings are in the elo scale with 1200 as the average strength.
+func (b *bradleyTerry) Ratings() []Rating {
+	// Each item plays a virtual draw against an item of strength 1, so items
+	// without wins or losses get finite strengths
+	p := make([]float64, b.n)
+	for i := range p {
+		p[i] = 1
+	}
+	next := make([]float64, b.n)
+	for iter := 0; iter < bradleyTerryMaxIters; iter++ {
+		denom := make([]float64, b.n)
+		for i := range denom {
+			denom[i] = bradleyTerryPrior / (p[i] + 1)
+		}
+		for pair, n := range b.games {
+			i, j := pair[0], pair[1]
+			d := n / (p[i] + p[j])
+			denom[i] += d
+			denom[j] += d
+		}
+		var diff float64
+		for i := range next {
+			next[i] = (b.wins[i] + bradleyTerryPrior/2) / denom[i]
+			diff = math.Max(diff, math.Abs(math.Log(next[i]/p[i])))
+		}
+		p, next = next, p
+		if diff < bradleyTerryTolerance {
+			break
+		}
+	}
+
+	values := make([]float64, b.n)
+	for i := range values {
+		values[i] = eloInitial + eloScale*math.Log(p[i])
+	}
+	devs := logisticDeviations(values, b.games, bradleyTerryPrior)
+	ratings := make([]Rating, b.n)
+	for i := range ratings {
+		ratings[i] = Rating{Value: values[i], Deviation: devs[i]}
+	}
+	return ratings
+}
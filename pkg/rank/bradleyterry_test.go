@@ -0,0 +1,124 @@
+package rank
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type comparison struct {
+	a, b  int
+	score float64
+}
+
+func TestBradleyTerryOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		comparisons []comparison
+	}{
+		{
+			name: "transitive",
+			n:    4,
+			comparisons: []comparison{
+				{0, 1, 1}, {1, 2, 1}, {2, 3, 1}, {0, 2, 1}, {1, 3, 1}, {0, 3, 1},
+			},
+		},
+		{
+			name: "mixed",
+			n:    4,
+			comparisons: []comparison{
+				{0, 1, 1}, {1, 2, 1}, {2, 0, 0}, {0, 3, 0.5}, {3, 1, 0.75},
+				{2, 3, 1}, {0, 1, 0}, {1, 2, 0.25}, {3, 0, 1},
+			},
+		},
+		{
+			name: "repeated pairs",
+			n:    3,
+			comparisons: []comparison{
+				{0, 1, 1}, {0, 1, 1}, {0, 1, 0}, {1, 2, 1}, {1, 2, 0.5}, {2, 0, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := fitBradleyTerry(tt.n, tt.comparisons)
+
+			// Reversed and shuffled comparisons give the same ratings
+			reversed := make([]comparison, len(tt.comparisons))
+			for i, c := range tt.comparisons {
+				reversed[len(reversed)-1-i] = c
+			}
+			shuffled := make([]comparison, len(tt.comparisons))
+			copy(shuffled, tt.comparisons)
+			rnd := rand.New(rand.NewSource(1))
+			rnd.Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+			for name, cs := range map[string][]comparison{"reversed": reversed, "shuffled": shuffled} {
+				got := fitBradleyTerry(tt.n, cs)
+				for i := range want {
+					if !near(got[i].Value, want[i].Value, 1e-6) || !near(got[i].Deviation, want[i].Deviation, 1e-6) {
+						t.Errorf("%s item %d: got %+v, want %+v", name, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBradleyTerryRanking(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		comparisons []comparison
+		// better are pairs of items where the first is rated higher
+		better [][2]int
+	}{
+		{
+			name: "transitive",
+			n:    4,
+			comparisons: []comparison{
+				{3, 2, 1}, {2, 1, 1}, {1, 0, 1}, {3, 1, 1}, {2, 0, 1}, {3, 0, 1},
+			},
+			better: [][2]int{{3, 2}, {2, 1}, {1, 0}},
+		},
+		{
+			name: "strength of schedule",
+			n:    5,
+			// 1 and 2 win half of their games, but 1 plays the strongest item
+			comparisons: []comparison{
+				{0, 3, 1}, {0, 4, 1}, {0, 3, 1}, {3, 4, 1},
+				{1, 0, 1}, {0, 1, 1},
+				{2, 4, 1}, {4, 2, 1},
+			},
+			better: [][2]int{{0, 3}, {3, 4}, {1, 2}},
+		},
+		{
+			name: "graded",
+			n:    3,
+			comparisons: []comparison{
+				{0, 1, 0.75}, {1, 0, 0.25}, {1, 2, 0.5}, {2, 1, 0.5},
+			},
+			better: [][2]int{{0, 1}, {0, 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratings := fitBradleyTerry(tt.n, tt.comparisons)
+			for _, pair := range tt.better {
+				a, b := pair[0], pair[1]
+				if ratings[a].Value <= ratings[b].Value {
+					t.Errorf("item %d isn't rated above item %d: %+v", a, b, ratings)
+				}
+			}
+		})
+	}
+}
+
+func fitBradleyTerry(n int, comparisons []comparison) []Rating {
+	b := newBradleyTerry(n)
+	for _, c := range comparisons {
+		b.Update(c.a, c.b, c.score)
+	}
+	return b.Ratings()
+}
@@ -0,0 +1,123 @@
+package rank
+
+import (
+	"math"
+	"sort"
+)
+
+// KendallTau returns the Kendall tau-b rank correlation between a and b.
+// It is 1 if both have the same order, -1 if the order is reversed and 0 if
+// they are unrelated.
+func KendallTau(a, b []float64) float64 {
+	var concordant, discordant, tiesA, tiesB float64
+	for i := range a {
+		for j := i + 1; j < len(a); j++ {
+			da := sign(a[i] - a[j])
+			db := sign(b[i] - b[j])
+			switch {
+			case da == 0 && db == 0:
+			case da == 0:
+				tiesA++
+			case db == 0:
+				tiesB++
+			case da == db:
+				concordant++
+			default:
+				discordant++
+			}
+		}
+	}
+	denom := math.Sqrt((concordant + discordant + tiesA) * (concordant + discordant + tiesB))
+	if denom == 0 {
+		return 0
+	}
+	return (concordant - discordant) / denom
+}
+
+// Spearman returns the Spearman rank correlation between a and b, tied values
+// get the average of their ranks.
+func Spearman(a, b []float64) float64 {
+	return pearson(ranks(a), ranks(b))
+}
+
+// ranks returns the rank of each value, starting at 1.
+func ranks(vs []float64) []float64 {
+	order := make([]int, len(vs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return vs[order[a]] < vs[order[b]]
+	})
+	rs := make([]float64, len(vs))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && vs[order[j+1]] == vs[order[i]] {
+			j++
+		}
+		// Average rank of the tied values
+		r := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			rs[order[k]] = r
+		}
+		i = j + 1
+	}
+	return rs
+}
+
+// pearson returns the Pearson correlation between a and b.
+func pearson(a, b []float64) float64 {
+	n := float64(len(a))
+	if n == 0 {
+		return 0
+	}
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i] / n
+		meanB += b[i] / n
+	}
+	var cov, varA, varB float64
+	for i := range a {
+		cov += (a[i] - meanA) * (b[i] - meanB)
+		varA += (a[i] - meanA) * (a[i] - meanA)
+		varB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+func sign(v float64) float64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// Stability returns the Kendall tau between the previous and the current
+// ratings of the top k items of the current ratings.
+// If k is 0, all the items are used.
+func Stability(prev, curr []Rating, k int) float64 {
+	order := make([]int, len(curr))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return curr[order[a]].Value > curr[order[b]].Value
+	})
+	if k > 0 && k < len(order) {
+		order = order[:k]
+	}
+	a := make([]float64, len(order))
+	b := make([]float64, len(order))
+	for n, i := range order {
+		a[n] = prev[i].Value
+		b[n] = curr[i].Value
+	}
+	return KendallTau(a, b)
+}
@@ -0,0 +1,85 @@
+package rank
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKendallTau(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{name: "same order", a: []float64{1, 2, 3, 4}, b: []float64{10, 20, 30, 40}, want: 1},
+		{name: "reversed", a: []float64{1, 2, 3, 4}, b: []float64{4, 3, 2, 1}, want: -1},
+		{name: "one swap", a: []float64{1, 2, 3, 4}, b: []float64{1, 3, 2, 4}, want: 4.0 / 6},
+		{name: "ties", a: []float64{1, 2, 2, 3}, b: []float64{1, 2, 3, 4}, want: 5 / math.Sqrt(30)},
+		{name: "all tied", a: []float64{1, 1, 1}, b: []float64{1, 2, 3}, want: 0},
+		{name: "empty", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := KendallTau(tt.a, tt.b)
+			if !near(got, tt.want, 1e-9) {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+			// The correlation is symmetric
+			if rev := KendallTau(tt.b, tt.a); !near(rev, got, 1e-9) {
+				t.Errorf("got %f swapping the arguments, want %f", rev, got)
+			}
+		})
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{name: "same order", a: []float64{1, 2, 3, 4}, b: []float64{1, 10, 100, 1000}, want: 1},
+		{name: "reversed", a: []float64{1, 2, 3, 4}, b: []float64{4, 3, 2, 1}, want: -1},
+		{name: "one swap", a: []float64{1, 2, 3, 4}, b: []float64{1, 3, 2, 4}, want: 0.8},
+		{name: "ties", a: []float64{1, 2, 2, 3}, b: []float64{1, 2, 3, 4}, want: 4.5 / math.Sqrt(22.5)},
+		{name: "all tied", a: []float64{1, 1, 1}, b: []float64{1, 2, 3}, want: 0},
+		{name: "empty", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Spearman(tt.a, tt.b)
+			if !near(got, tt.want, 1e-9) {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStability(t *testing.T) {
+	ratings := func(vs ...float64) []Rating {
+		rs := make([]Rating, len(vs))
+		for i, v := range vs {
+			rs[i] = Rating{Value: v}
+		}
+		return rs
+	}
+	tests := []struct {
+		name       string
+		prev, curr []Rating
+		k          int
+		want       float64
+	}{
+		{name: "stable", prev: ratings(1, 2, 3, 4), curr: ratings(2, 4, 6, 8), want: 1},
+		{name: "reversed", prev: ratings(4, 3, 2, 1), curr: ratings(1, 2, 3, 4), want: -1},
+		// The top 2 items are 3 and 2, which keep their order
+		{name: "top k", prev: ratings(4, 3, 1, 2), curr: ratings(1, 2, 3, 4), k: 2, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Stability(tt.prev, tt.curr, tt.k)
+			if !near(got, tt.want, 1e-9) {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package rank
+
+import "math"
+
+// elo is an online Elo rating system.
+type elo struct {
+	k       float64
+	ratings []float64
+	games   games
+}
+
+// Default elo parameters
+const (
+	eloInitial = 1200.0
+	eloK       = 32.0
+)
+
+func newElo(n int, k float64) *elo {
+	if k <= 0 {
+		k = eloK
+	}
+	ratings := make([]float64, n)
+	for i := range ratings {
+		ratings[i] = eloInitial
+	}
+	return &elo{
+		k:       k,
+		ratings: ratings,
+		games:   games{},
+	}
+}
+
+// Update implements Ranker.
+func (e *elo) Update(a, b int, score float64) {
+	e.ratings[a], e.ratings[b] = updateEloRatings(e.k, e.ratings[a], e.ratings[b], score, 1-score)
+	e.games.add(a, b)
+}
+
+// Ratings implements Ranker.
+// Elo doesn't track uncertainty, so the deviation is estimated from the
+// comparisons played.
+func (e *elo) Ratings() []Rating {
+	devs := logisticDeviations(e.ratings, e.games, 0)
+	ratings := make([]Rating, len(e.ratings))
+	for i, r := range e.ratings {
+		ratings[i] = Rating{Value: r, Deviation: devs[i]}
+	}
+	return ratings
+}
+
+// Function to calculate the expected score
+func expectedScore(ratingA, ratingB float64) float64 {
+	return 1.0 / (1.0 + math.Pow(10, (ratingB-ratingA)/400))
+}
+
+// Function to update Elo ratings
+func updateEloRatings(k, ratingA, ratingB, scoreA, scoreB float64) (float64, float64) {
+	expectedA := expectedScore(ratingA, ratingB)
+	expectedB := expectedScore(ratingB, ratingA)
+
+	newRatingA := ratingA + k*(scoreA-expectedA)
+	newRatingB := ratingB + k*(scoreB-expectedB)
+
+	return newRatingA, newRatingB
+}
@@ -0,0 +1,26 @@
+package rank
+
+import "testing"
+
+func TestElo(t *testing.T) {
+	tests := []struct {
+		name    string
+		k       float64
+		ratings [2]float64
+		score   float64
+		want    [2]float64
+	}{
+		{name: "win", k: 32, ratings: [2]float64{1200, 1200}, score: 1, want: [2]float64{1216, 1184}},
+		{name: "draw", k: 32, ratings: [2]float64{1200, 1200}, score: 0.5, want: [2]float64{1200, 1200}},
+		{name: "upset", k: 32, ratings: [2]float64{1000, 1400}, score: 1, want: [2]float64{1000 + 32*10.0/11, 1400 - 32*10.0/11}},
+		{name: "expected win", k: 16, ratings: [2]float64{1400, 1000}, score: 1, want: [2]float64{1400 + 16*1.0/11, 1000 - 16*1.0/11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := updateEloRatings(tt.k, tt.ratings[0], tt.ratings[1], tt.score, 1-tt.score)
+			if !near(a, tt.want[0], 1e-9) || !near(b, tt.want[1], 1e-9) {
+				t.Errorf("got %f %f, want %f %f", a, b, tt.want[0], tt.want[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package rank
+
+import "math"
+
+// glicko2 is the Glicko-2 rating system, each comparison is treated as a
+// rating period with a single game.
+// See http://www.glicko.net/glicko/glicko2.pdf
+type glicko2 struct {
+	players []glickoPlayer
+}
+
+type glickoPlayer struct {
+	mu    float64
+	phi   float64
+	sigma float64
+}
+
+// Default glicko-2 parameters
+const (
+	glickoInitial    = 1500.0
+	glickoDeviation  = 350.0
+	glickoVolatility = 0.06
+	glickoTau        = 0.5
+	glickoScale      = 173.7178
+	glickoEpsilon    = 0.000001
+)
+
+func newGlicko2(n int) *glicko2 {
+	players := make([]glickoPlayer, n)
+	for i := range players {
+		players[i] = glickoPlayer{
+			mu:    0,
+			phi:   glickoDeviation / glickoScale,
+			sigma: glickoVolatility,
+		}
+	}
+	return &glicko2{players: players}
+}
+
+// Update implements Ranker.
+func (g *glicko2) Update(a, b int, score float64) {
+	pa, pb := g.players[a], g.players[b]
+	g.players[a] = pa.update([]glickoGame{{opponent: pb, score: score}})
+	g.players[b] = pb.update([]glickoGame{{opponent: pa, score: 1 - score}})
+}
+
+// Ratings implements Ranker.
+func (g *glicko2) Ratings() []Rating {
+	ratings := make([]Rating, len(g.players))
+	for i, p := range g.players {
+		ratings[i] = Rating{
+			Value:     glickoInitial + glickoScale*p.mu,
+			Deviation: glickoScale * p.phi,
+		}
+	}
+	return ratings
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// glickoGame is a game against an opponent with the score of the player.
+type glickoGame struct {
+	opponent glickoPlayer
+	score    float64
+}
+
+// update returns the player after the games of a rating period.
+func (p glickoPlayer) update(games []glickoGame) glickoPlayer {
+	var vInv, sum float64
+	for _, game := range games {
+		o := game.opponent
+		g := glickoG(o.phi)
+		e := 1 / (1 + math.Exp(-g*(p.mu-o.mu)))
+		vInv += g * g * e * (1 - e)
+		sum += g * (game.score - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	// New volatility using the Illinois algorithm
+	phi2 := p.phi * p.phi
+	a := math.Log(p.sigma * p.sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi2 + v + ex
+		return ex*(delta*delta-phi2-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+	A := a
+	var B float64
+	if delta*delta > phi2+v {
+		B = math.Log(delta*delta - phi2 - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	sigma := math.Exp(A / 2)
+
+	// New deviation and rating
+	phiStar := math.Sqrt(phi2 + sigma*sigma)
+	phi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu := p.mu + phi*phi*sum
+	return glickoPlayer{mu: mu, phi: phi, sigma: sigma}
+}
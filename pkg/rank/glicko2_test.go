@@ -0,0 +1,61 @@
+package rank
+
+import "testing"
+
+func TestGlicko2(t *testing.T) {
+	player := func(rating, deviation float64) glickoPlayer {
+		return glickoPlayer{
+			mu:    (rating - glickoInitial) / glickoScale,
+			phi:   deviation / glickoScale,
+			sigma: glickoVolatility,
+		}
+	}
+	tests := []struct {
+		name          string
+		player        glickoPlayer
+		games         []glickoGame
+		wantRating    float64
+		wantDeviation float64
+		wantSigma     float64
+	}{
+		{
+			// Example of http://www.glicko.net/glicko/glicko2.pdf
+			name:   "paper",
+			player: player(1500, 200),
+			games: []glickoGame{
+				{opponent: player(1400, 30), score: 1},
+				{opponent: player(1550, 100), score: 0},
+				{opponent: player(1700, 300), score: 0},
+			},
+			wantRating:    1464.06,
+			wantDeviation: 151.52,
+			wantSigma:     0.05999,
+		},
+		{
+			name:   "draw between equals",
+			player: player(1500, 350),
+			games: []glickoGame{
+				{opponent: player(1500, 350), score: 0.5},
+			},
+			wantRating:    1500,
+			wantDeviation: 290.32,
+			wantSigma:     0.06,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.player.update(tt.games)
+			rating := glickoInitial + glickoScale*got.mu
+			deviation := glickoScale * got.phi
+			if !near(rating, tt.wantRating, 0.01) {
+				t.Errorf("rating: got %f, want %f", rating, tt.wantRating)
+			}
+			if !near(deviation, tt.wantDeviation, 0.01) {
+				t.Errorf("deviation: got %f, want %f", deviation, tt.wantDeviation)
+			}
+			if !near(got.sigma, tt.wantSigma, 0.00001) {
+				t.Errorf("volatility: got %f, want %f", got.sigma, tt.wantSigma)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package rank
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Pairing strategies
+const (
+	// PairingRandom compares each item with a random opponent
+	PairingRandom = "random"
+	// PairingSwiss pairs neighbours in the current ranking
+	PairingSwiss = "swiss"
+	// PairingActive pairs the items whose order is most uncertain
+	PairingActive = "active"
+)
+
+// Pairer chooses the pairs of items to compare in each round.
+type Pairer struct {
+	strategy string
+	n        int
+	played   games
+}
+
+// NewPairer creates a pairer for n items using the given strategy.
+func NewPairer(strategy string, n int) (*Pairer, error) {
+	switch strategy {
+	case "":
+		strategy = PairingRandom
+	case PairingRandom, PairingSwiss, PairingActive:
+	default:
+		return nil, fmt.Errorf("rank: unknown pairing strategy %q", strategy)
+	}
+	return &Pairer{
+		strategy: strategy,
+		n:        n,
+		played:   games{},
+	}, nil
+}
+
+// Round returns the pairs to compare in the next round using the current
+// ratings.
+func (p *Pairer) Round(ratings []Rating) [][2]int {
+	if p.n < 2 {
+		return nil
+	}
+	var pairs [][2]int
+	switch p.strategy {
+	case PairingSwiss:
+		pairs = p.swiss(ratings)
+	case PairingActive:
+		pairs = p.active(ratings)
+	default:
+		pairs = p.random()
+	}
+	for _, pair := range pairs {
+		p.played.add(pair[0], pair[1])
+	}
+	return pairs
+}
+
+// random pairs each item with a random opponent.
+func (p *Pairer) random() [][2]int {
+	var pairs [][2]int
+	for i := 0; i < p.n; i++ {
+		j := i
+		for j == i {
+			j = rand.Intn(p.n)
+		}
+		pairs = append(pairs, [2]int{i, j})
+	}
+	return pairs
+}
+
+// swiss sorts the items by rating and pairs each item with the closest one
+// that it hasn't been compared with yet.
+func (p *Pairer) swiss(ratings []Rating) [][2]int {
+	// Shuffle first so items with the same rating are paired randomly
+	order := rand.Perm(p.n)
+	sort.SliceStable(order, func(a, b int) bool {
+		return ratings[order[a]].Value > ratings[order[b]].Value
+	})
+
+	paired := make([]bool, p.n)
+	var pairs [][2]int
+	for a, i := range order {
+		if paired[i] {
+			continue
+		}
+		j := -1
+		for _, k := range order[a+1:] {
+			if paired[k] {
+				continue
+			}
+			if j < 0 {
+				j = k
+			}
+			if p.count(i, k) == 0 {
+				j = k
+				break
+			}
+		}
+		if j < 0 {
+			break
+		}
+		paired[i], paired[j] = true, true
+		pairs = append(pairs, [2]int{i, j})
+	}
+	return pairs
+}
+
+// active greedily pairs the items with the highest expected information.
+// A pair is informative when the probability of each item being better is
+// close to 50% and the ratings are uncertain.
+// Pairs already compared are penalized.
+func (p *Pairer) active(ratings []Rating) [][2]int {
+	type candidate struct {
+		i, j int
+		info float64
+	}
+	var candidates []candidate
+	for i := 0; i < p.n; i++ {
+		for j := i + 1; j < p.n; j++ {
+			ri, rj := ratings[i], ratings[j]
+			variance := ri.Deviation*ri.Deviation + rj.Deviation*rj.Deviation
+			q := 0.5
+			if diff := ri.Value - rj.Value; variance > 0 {
+				q = normCDF(diff / math.Sqrt(variance))
+			} else if diff != 0 {
+				q = 0
+			}
+			candidates = append(candidates, candidate{
+				i:    i,
+				j:    j,
+				info: q * (1 - q) * (variance + 1) / (1 + p.count(i, j)),
+			})
+		}
+	}
+	// Shuffle first so pairs with the same information are chosen randomly
+	rand.Shuffle(len(candidates), func(a, b int) {
+		candidates[a], candidates[b] = candidates[b], candidates[a]
+	})
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].info > candidates[b].info
+	})
+
+	paired := make([]bool, p.n)
+	var pairs [][2]int
+	for _, c := range candidates {
+		if len(pairs) >= p.n/2 {
+			break
+		}
+		if paired[c.i] || paired[c.j] {
+			continue
+		}
+		paired[c.i], paired[c.j] = true, true
+		// Randomize which item is iterated first
+		if rand.Intn(2) == 0 {
+			c.i, c.j = c.j, c.i
+		}
+		pairs = append(pairs, [2]int{c.i, c.j})
+	}
+	return pairs
+}
+
+// count returns the number of times a pair has been compared.
+func (p *Pairer) count(i, j int) float64 {
+	if i > j {
+		i, j = j, i
+	}
+	return p.played[[2]int{i, j}]
+}
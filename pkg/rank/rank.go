@@ -0,0 +1,100 @@
+package rank
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rating is the rating of an item along with its uncertainty.
+type Rating struct {
+	Value float64
+	// Deviation is the standard deviation of the rating
+	Deviation float64
+}
+
+// Ranker rates items using the outcomes of pairwise comparisons.
+type Ranker interface {
+	// Update updates the ratings with the outcome of a comparison between
+	// items a and b. The score is 1 if a wins, 0 if b wins and 0.5 for a draw.
+	// Scores in between are graded preferences.
+	Update(a, b int, score float64)
+	// Ratings returns the current ratings of the items.
+	Ratings() []Rating
+}
+
+// Algorithms
+const (
+	Elo          = "elo"
+	Glicko2      = "glicko2"
+	TrueSkill    = "trueskill"
+	BradleyTerry = "bradley-terry"
+)
+
+type Config struct {
+	// Algorithm is the ranking algorithm (elo, glicko2, trueskill or
+	// bradley-terry)
+	Algorithm string
+	// N is the number of items
+	N int
+	// K is the K-factor of the elo algorithm
+	K float64
+}
+
+// New creates a ranker using the configured algorithm.
+func New(cfg *Config) (Ranker, error) {
+	switch cfg.Algorithm {
+	case "", Elo:
+		return newElo(cfg.N, cfg.K), nil
+	case Glicko2:
+		return newGlicko2(cfg.N), nil
+	case TrueSkill:
+		return newTrueSkill(cfg.N), nil
+	case BradleyTerry:
+		return newBradleyTerry(cfg.N), nil
+	default:
+		return nil, fmt.Errorf("rank: unknown algorithm %q", cfg.Algorithm)
+	}
+}
+
+// eloScale is the scale of logistic ratings, a difference of 400 points
+// means the stronger item is expected to win 10 times more often.
+const eloScale = 400 / math.Ln10
+
+// maxDeviation is the deviation of items without comparisons
+const maxDeviation = 350.0
+
+// games counts the comparisons between pairs of items.
+type games map[[2]int]float64
+
+func (g games) add(a, b int) {
+	if a > b {
+		a, b = b, a
+	}
+	g[[2]int{a, b}]++
+}
+
+// logisticDeviations returns the standard errors of logistic ratings using the
+// Fisher information of the comparisons played.
+// The prior adds information to each item as if it had played against an
+// item with the same rating.
+func logisticDeviations(values []float64, g games, prior float64) []float64 {
+	info := make([]float64, len(values))
+	for i := range info {
+		info[i] = prior * 0.25
+	}
+	for pair, n := range g {
+		a, b := pair[0], pair[1]
+		p := 1.0 / (1.0 + math.Exp(-(values[a]-values[b])/eloScale))
+		info[a] += n * p * (1 - p)
+		info[b] += n * p * (1 - p)
+	}
+	devs := make([]float64, len(values))
+	for i, v := range info {
+		if v == 0 {
+			devs[i] = maxDeviation
+			continue
+		}
+		devs[i] = math.Min(maxDeviation, eloScale/math.Sqrt(v))
+	}
+	return devs
+}
@@ -0,0 +1,132 @@
+package rank
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSymmetric(t *testing.T) {
+	algorithms := []struct {
+		name    string
+		initial float64
+	}{
+		{name: Elo, initial: eloInitial},
+		{name: Glicko2, initial: glickoInitial},
+		{name: TrueSkill, initial: trueSkillMu},
+		{name: BradleyTerry, initial: eloInitial},
+	}
+	tests := []struct {
+		name  string
+		score float64
+	}{
+		{name: "draw", score: 0.5},
+		{name: "win", score: 1},
+		{name: "loss", score: 0},
+		{name: "graded", score: 0.75},
+	}
+	for _, alg := range algorithms {
+		for _, tt := range tests {
+			t.Run(alg.name+"/"+tt.name, func(t *testing.T) {
+				// Bradley-Terry is fitted iteratively, so allow for the tolerance
+				// of the fit
+				const tolerance = 1e-4
+				r := newRanker(t, alg.name, 2)
+				r.Update(0, 1, tt.score)
+				got := r.Ratings()
+
+				// Both items move the same distance from the initial rating
+				if !near(got[0].Value-alg.initial, alg.initial-got[1].Value, tolerance) {
+					t.Errorf("ratings aren't symmetric: %+v", got)
+				}
+				if !near(got[0].Deviation, got[1].Deviation, tolerance) {
+					t.Errorf("deviations aren't symmetric: %+v", got)
+				}
+				if tt.score == 0.5 && !near(got[0].Value, got[1].Value, tolerance) {
+					t.Errorf("draw changed the order: %+v", got)
+				}
+
+				// Swapping the items gives the same ratings
+				swapped := newRanker(t, alg.name, 2)
+				swapped.Update(1, 0, 1-tt.score)
+				want := swapped.Ratings()
+				for i := range got {
+					if !near(got[i].Value, want[i].Value, tolerance) || !near(got[i].Deviation, want[i].Deviation, tolerance) {
+						t.Errorf("item %d: got %+v, want %+v", i, got[i], want[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLogisticDeviations(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		games  games
+		prior  float64
+		want   []float64
+	}{
+		{
+			name:   "no games",
+			values: []float64{1200, 1200},
+			games:  games{},
+			want:   []float64{maxDeviation, maxDeviation},
+		},
+		{
+			name:   "prior",
+			values: []float64{1200, 1200},
+			games:  games{},
+			prior:  1,
+			want:   []float64{2 * eloScale, 2 * eloScale},
+		},
+		{
+			name:   "equal ratings",
+			values: []float64{1200, 1200, 1200},
+			games:  games{{0, 1}: 4},
+			want:   []float64{eloScale, eloScale, maxDeviation},
+		},
+		{
+			name:   "more games",
+			values: []float64{1200, 1200},
+			games:  games{{0, 1}: 16},
+			want:   []float64{eloScale / 2, eloScale / 2},
+		},
+		{
+			name:   "uneven ratings",
+			values: []float64{1200 + eloScale*math.Log(3), 1200},
+			games:  games{{0, 1}: 4},
+			// p = 0.75, so each game adds 0.1875 of information
+			want: []float64{eloScale / math.Sqrt(0.75), eloScale / math.Sqrt(0.75)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logisticDeviations(tt.values, tt.games, tt.prior)
+			for i := range tt.want {
+				if !near(got[i], tt.want[i], 1e-6) {
+					t.Errorf("item %d: got %f, want %f", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&Config{Algorithm: "unknown", N: 2}); err == nil {
+		t.Error("expected error for unknown algorithm")
+	}
+}
+
+func newRanker(t *testing.T, algorithm string, n int) Ranker {
+	t.Helper()
+	r, err := New(&Config{Algorithm: algorithm, N: n})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
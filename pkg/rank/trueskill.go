@@ -0,0 +1,123 @@
+package rank
+
+import "math"
+
+// trueSkill is the TrueSkill rating system for two player games.
+// See https://www.microsoft.com/en-us/research/publication/trueskilltm-a-bayesian-skill-rating-system/
+type trueSkill struct {
+	mu     []float64
+	sigma2 []float64
+}
+
+// Default trueskill parameters
+const (
+	trueSkillMu        = 25.0
+	trueSkillSigma     = trueSkillMu / 3
+	trueSkillBeta      = trueSkillSigma / 2
+	trueSkillTau       = trueSkillSigma / 100
+	trueSkillDrawProb  = 0.1
+	trueSkillMinFactor = 0.0001
+)
+
+func newTrueSkill(n int) *trueSkill {
+	mu := make([]float64, n)
+	sigma2 := make([]float64, n)
+	for i := range mu {
+		mu[i] = trueSkillMu
+		sigma2[i] = trueSkillSigma * trueSkillSigma
+	}
+	return &trueSkill{mu: mu, sigma2: sigma2}
+}
+
+// Update implements Ranker.
+// Scores above 0.5 are wins of a, below 0.5 are wins of b and 0.5 is a draw.
+// Graded preferences interpolate between the updates of a draw and a win by
+// the margin, so a score of 0.75 is halfway between both.
+func (t *trueSkill) Update(a, b int, score float64) {
+	if score < 0.5 {
+		a, b = b, a
+		score = 1 - score
+	}
+	win := 2 * (score - 0.5)
+
+	// Add dynamics
+	sa := t.sigma2[a] + trueSkillTau*trueSkillTau
+	sb := t.sigma2[b] + trueSkillTau*trueSkillTau
+
+	c2 := 2*trueSkillBeta*trueSkillBeta + sa + sb
+	c := math.Sqrt(c2)
+	eps := drawMargin(trueSkillDrawProb) / c
+	x := (t.mu[a] - t.mu[b]) / c
+
+	v := win*vWin(x, eps) + (1-win)*vDraw(x, eps)
+	w := win*wWin(x, eps) + (1-win)*wDraw(x, eps)
+
+	t.mu[a] += sa / c * v
+	t.mu[b] -= sb / c * v
+	t.sigma2[a] = sa * math.Max(1-sa/c2*w, trueSkillMinFactor)
+	t.sigma2[b] = sb * math.Max(1-sb/c2*w, trueSkillMinFactor)
+}
+
+// Ratings implements Ranker.
+func (t *trueSkill) Ratings() []Rating {
+	ratings := make([]Rating, len(t.mu))
+	for i := range t.mu {
+		ratings[i] = Rating{Value: t.mu[i], Deviation: math.Sqrt(t.sigma2[i])}
+	}
+	return ratings
+}
+
+// drawMargin returns the performance margin for the draw probability.
+func drawMargin(p float64) float64 {
+	return normInv((p+1)/2) * math.Sqrt2 * trueSkillBeta
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func normInv(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+func vWin(t, e float64) float64 {
+	d := normCDF(t - e)
+	if d < 1e-12 {
+		return -(t - e)
+	}
+	return normPDF(t-e) / d
+}
+
+func wWin(t, e float64) float64 {
+	v := vWin(t, e)
+	return v * (v + t - e)
+}
+
+func vDraw(t, e float64) float64 {
+	a := math.Abs(t)
+	d := normCDF(e-a) - normCDF(-e-a)
+	var v float64
+	if d < 1e-12 {
+		v = -a - e
+	} else {
+		v = (normPDF(-e-a) - normPDF(e-a)) / d
+	}
+	if t < 0 {
+		return -v
+	}
+	return v
+}
+
+func wDraw(t, e float64) float64 {
+	a := math.Abs(t)
+	d := normCDF(e-a) - normCDF(-e-a)
+	if d < 1e-12 {
+		return 1
+	}
+	v := vDraw(a, e)
+	return v*v + ((e-a)*normPDF(e-a)+(e+a)*normPDF(e+a))/d
+}
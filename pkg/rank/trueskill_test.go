@@ -0,0 +1,60 @@
+package rank
+
+import "testing"
+
+func TestTrueSkill(t *testing.T) {
+	// Expected values of the reference implementation for new players
+	// (https://trueskill.org)
+	tests := []struct {
+		name  string
+		score float64
+		want  []Rating
+	}{
+		{
+			name:  "win",
+			score: 1,
+			want:  []Rating{{Value: 29.396, Deviation: 7.171}, {Value: 20.604, Deviation: 7.171}},
+		},
+		{
+			name:  "loss",
+			score: 0,
+			want:  []Rating{{Value: 20.604, Deviation: 7.171}, {Value: 29.396, Deviation: 7.171}},
+		},
+		{
+			name:  "draw",
+			score: 0.5,
+			want:  []Rating{{Value: 25, Deviation: 6.458}, {Value: 25, Deviation: 6.458}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTrueSkill(2)
+			r.Update(0, 1, tt.score)
+			got := r.Ratings()
+			for i := range tt.want {
+				if !near(got[i].Value, tt.want[i].Value, 0.001) || !near(got[i].Deviation, tt.want[i].Deviation, 0.001) {
+					t.Errorf("item %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTrueSkillMargin(t *testing.T) {
+	// Soft outcomes are between a draw and a full win
+	rating := func(score float64) float64 {
+		r := newTrueSkill(2)
+		r.Update(0, 1, score)
+		return r.Ratings()[0].Value
+	}
+	scores := []float64{0.5, 0.51, 0.75, 0.99, 1}
+	for i := 1; i < len(scores); i++ {
+		prev, cur := rating(scores[i-1]), rating(scores[i])
+		if cur <= prev {
+			t.Errorf("score %v: got rating %v, want more than %v of score %v", scores[i], cur, prev, scores[i-1])
+		}
+	}
+	if got, want := rating(0.75), (rating(0.5)+rating(1))/2; !near(got, want, 1e-9) {
+		t.Errorf("got rating %v for 0.75, want %v", got, want)
+	}
+}
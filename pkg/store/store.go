@@ -0,0 +1,294 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/igolaizola/twai/pkg/twitter"
+	_ "modernc.org/sqlite"
+)
+
+// Store is a SQLite database of tweets, metric snapshots and scores.
+type Store struct {
+	db *sql.DB
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS posts (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	time TIMESTAMP NOT NULL,
+	data TEXT NOT NULL,
+	first_seen TIMESTAMP NOT NULL,
+	last_seen TIMESTAMP NOT NULL
+);
+CREATE TABLE IF NOT EXISTS metrics (
+	post_id TEXT NOT NULL REFERENCES posts(id),
+	time TIMESTAMP NOT NULL,
+	comments INTEGER NOT NULL,
+	retweets INTEGER NOT NULL,
+	likes INTEGER NOT NULL,
+	views INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS metrics_post_id ON metrics(post_id);
+CREATE TABLE IF NOT EXISTS scores (
+	post_id TEXT NOT NULL REFERENCES posts(id),
+	kind TEXT NOT NULL,
+	model TEXT NOT NULL,
+	prompt TEXT NOT NULL,
+	score REAL NOT NULL,
+	data TEXT NOT NULL DEFAULT '',
+	time TIMESTAMP NOT NULL,
+	PRIMARY KEY (post_id, kind, model, prompt)
+);
+`
+
+// migrations add the columns missing in databases created by older versions.
+var migrations = []struct {
+	table, column, definition string
+}{
+	{"scores", "data", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// Open opens the database at the given path, creating it if needed.
+func Open(ctx context.Context, path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("store: couldn't open database: %w", err)
+	}
+	// SQLite doesn't support concurrent writes
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("store: couldn't create schema: %w", err)
+	}
+	if err := migrate(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// migrate adds the missing columns to the tables.
+func migrate(ctx context.Context, db *sql.DB) error {
+	for _, m := range migrations {
+		var n int
+		if err := db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, m.table, m.column,
+		).Scan(&n); err != nil {
+			return fmt.Errorf("store: couldn't get columns of %s: %w", m.table, err)
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.ExecContext(ctx,
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition),
+		); err != nil {
+			return fmt.Errorf("store: couldn't add column %s to %s: %w", m.column, m.table, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// UpsertPosts inserts or updates the posts and stores a snapshot of their
+// metrics.
+func (s *Store) UpsertPosts(ctx context.Context, posts []*twitter.Post) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: couldn't begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for _, p := range posts {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("store: couldn't marshal post %s: %w", p.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO posts (id, user_id, time, data, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				user_id = excluded.user_id,
+				time = excluded.time,
+				data = excluded.data,
+				last_seen = excluded.last_seen`,
+			p.ID, p.UserID, p.Time.UTC(), string(data), now, now,
+		); err != nil {
+			return fmt.Errorf("store: couldn't upsert post %s: %w", p.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO metrics (post_id, time, comments, retweets, likes, views)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			p.ID, now, p.Comments, p.Retweets, p.Likes, p.Views,
+		); err != nil {
+			return fmt.Errorf("store: couldn't insert metrics %s: %w", p.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: couldn't commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AddPosts inserts the posts that aren't stored yet, without metric snapshots.
+// It is used for posts read from files, whose metrics may be stale.
+func (s *Store) AddPosts(ctx context.Context, posts []*twitter.Post) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: couldn't begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for _, p := range posts {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("store: couldn't marshal post %s: %w", p.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO posts (id, user_id, time, data, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO NOTHING`,
+			p.ID, p.UserID, p.Time.UTC(), string(data), now, now,
+		); err != nil {
+			return fmt.Errorf("store: couldn't add post %s: %w", p.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: couldn't commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Posts returns all the posts ordered from newest to oldest.
+func (s *Store) Posts(ctx context.Context) ([]*twitter.Post, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM posts ORDER BY time DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("store: couldn't query posts: %w", err)
+	}
+	defer rows.Close()
+	var posts []*twitter.Post
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("store: couldn't scan post: %w", err)
+		}
+		var p twitter.Post
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, fmt.Errorf("store: couldn't unmarshal post: %w", err)
+		}
+		posts = append(posts, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: couldn't iterate posts: %w", err)
+	}
+	return posts, nil
+}
+
+// Metric is a snapshot of the metrics of a post.
+type Metric struct {
+	Time     time.Time
+	Comments int
+	Retweets int
+	Likes    int
+	Views    int
+}
+
+// Metrics returns the metric snapshots of a post ordered by time.
+func (s *Store) Metrics(ctx context.Context, postID string) ([]*Metric, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT time, comments, retweets, likes, views FROM metrics
+		WHERE post_id = ? ORDER BY time`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("store: couldn't query metrics: %w", err)
+	}
+	defer rows.Close()
+	var metrics []*Metric
+	for rows.Next() {
+		var m Metric
+		if err := rows.Scan(&m.Time, &m.Comments, &m.Retweets, &m.Likes, &m.Views); err != nil {
+			return nil, fmt.Errorf("store: couldn't scan metric: %w", err)
+		}
+		metrics = append(metrics, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: couldn't iterate metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// KindScore is the kind of the scores of the score command, rankings use the
+// name of the ranking algorithm as kind.
+const KindScore = "score"
+
+// Score is the result of scoring a post with a model and a prompt.
+type Score struct {
+	PostID string
+	Kind   string
+	Model  string
+	Prompt string
+	Score  float64
+	// Data are the details of the score, like the score of each criterion,
+	// encoded by the caller
+	Data string
+}
+
+// SaveScores inserts or replaces the scores.
+func (s *Store) SaveScores(ctx context.Context, scores []*Score) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: couldn't begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	for _, sc := range scores {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO scores (post_id, kind, model, prompt, score, data, time)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(post_id, kind, model, prompt) DO UPDATE SET
+				score = excluded.score,
+				data = excluded.data,
+				time = excluded.time`,
+			sc.PostID, sc.Kind, sc.Model, sc.Prompt, sc.Score, sc.Data, now,
+		); err != nil {
+			return fmt.Errorf("store: couldn't save score %s: %w", sc.PostID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: couldn't commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Scores returns the scores of the given kind, model and prompt by post id.
+func (s *Store) Scores(ctx context.Context, kind, model, prompt string) (map[string]*Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT post_id, score, data FROM scores
+		WHERE kind = ? AND model = ? AND prompt = ?`, kind, model, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("store: couldn't query scores: %w", err)
+	}
+	defer rows.Close()
+	scores := map[string]*Score{}
+	for rows.Next() {
+		sc := &Score{Kind: kind, Model: model, Prompt: prompt}
+		if err := rows.Scan(&sc.PostID, &sc.Score, &sc.Data); err != nil {
+			return nil, fmt.Errorf("store: couldn't scan score: %w", err)
+		}
+		scores[sc.PostID] = sc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: couldn't iterate scores: %w", err)
+	}
+	return scores, nil
+}
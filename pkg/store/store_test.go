@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/igolaizola/twai/pkg/twitter"
+)
+
+func TestUpsertPosts(t *testing.T) {
+	ctx := context.Background()
+	db := openStore(t, filepath.Join(t.TempDir(), "twai.db"))
+
+	older := &twitter.Post{ID: "1", UserID: "golang", Text: "Go 1.21 is released", Time: date(2023, 8, 8), Likes: 10}
+	newer := &twitter.Post{ID: "2", UserID: "golang", Text: "Go 1.22 is released", Time: date(2024, 2, 6), Likes: 5}
+	if err := db.UpsertPosts(ctx, []*twitter.Post{older, newer}); err != nil {
+		t.Fatal(err)
+	}
+	updated := *older
+	updated.Likes = 20
+	if err := db.UpsertPosts(ctx, []*twitter.Post{&updated}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Posts are updated and ordered from newest to oldest
+	posts, err := db.Posts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("got %d posts, want 2", len(posts))
+	}
+	for i, want := range []*twitter.Post{newer, &updated} {
+		if !reflect.DeepEqual(posts[i], want) {
+			t.Errorf("post %d: got %+v, want %+v", i, posts[i], want)
+		}
+	}
+
+	// Each upsert stores a snapshot of the metrics
+	tests := []struct {
+		id    string
+		likes []int
+	}{
+		{id: "1", likes: []int{10, 20}},
+		{id: "2", likes: []int{5}},
+		{id: "3", likes: nil},
+	}
+	for _, tt := range tests {
+		metrics, err := db.Metrics(ctx, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var likes []int
+		for _, m := range metrics {
+			likes = append(likes, m.Likes)
+		}
+		if !reflect.DeepEqual(likes, tt.likes) {
+			t.Errorf("post %s: got likes %v, want %v", tt.id, likes, tt.likes)
+		}
+	}
+}
+
+func TestAddPosts(t *testing.T) {
+	ctx := context.Background()
+	db := openStore(t, filepath.Join(t.TempDir(), "twai.db"))
+
+	scraped := &twitter.Post{ID: "1", UserID: "golang", Text: "Go 1.22 is released", Time: date(2024, 2, 6), Likes: 20}
+	if err := db.UpsertPosts(ctx, []*twitter.Post{scraped}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Added posts don't replace stored posts nor add metric snapshots
+	stale := *scraped
+	stale.Likes = 10
+	added := &twitter.Post{ID: "2", UserID: "golang", Text: "Go 1.23 is released", Time: date(2024, 8, 13), Likes: 5}
+	if err := db.AddPosts(ctx, []*twitter.Post{&stale, added}); err != nil {
+		t.Fatal(err)
+	}
+	posts, err := db.Posts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*twitter.Post{added, scraped}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("got %+v, want %+v", posts, want)
+	}
+	for _, tt := range []struct {
+		id   string
+		want int
+	}{{id: "1", want: 1}, {id: "2", want: 0}} {
+		metrics, err := db.Metrics(ctx, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(metrics) != tt.want {
+			t.Errorf("post %s: got %d snapshots, want %d", tt.id, len(metrics), tt.want)
+		}
+	}
+}
+
+func TestScores(t *testing.T) {
+	ctx := context.Background()
+	db := openStore(t, filepath.Join(t.TempDir(), "twai.db"))
+
+	scores := []*Score{
+		{PostID: "1", Kind: KindScore, Model: "llama3", Prompt: "rate", Score: 8, Data: `{"rationale":"clear"}`},
+		{PostID: "2", Kind: KindScore, Model: "llama3", Prompt: "rate", Score: 6},
+		{PostID: "1", Kind: KindScore, Model: "gpt-4o", Prompt: "rate", Score: 7},
+		{PostID: "1", Kind: KindScore, Model: "llama3", Prompt: "other", Score: 5},
+		{PostID: "1", Kind: "elo", Model: "llama3", Prompt: "rate", Score: 1516},
+	}
+	if err := db.SaveScores(ctx, scores); err != nil {
+		t.Fatal(err)
+	}
+	// Saving again replaces the score and its data
+	if err := db.SaveScores(ctx, []*Score{
+		{PostID: "2", Kind: KindScore, Model: "llama3", Prompt: "rate", Score: 4, Data: `{"rationale":"vague"}`},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		kind, model, prompt string
+		want                map[string]*Score
+	}{
+		{
+			kind: KindScore, model: "llama3", prompt: "rate",
+			want: map[string]*Score{
+				"1": {PostID: "1", Kind: KindScore, Model: "llama3", Prompt: "rate", Score: 8, Data: `{"rationale":"clear"}`},
+				"2": {PostID: "2", Kind: KindScore, Model: "llama3", Prompt: "rate", Score: 4, Data: `{"rationale":"vague"}`},
+			},
+		},
+		{
+			kind: KindScore, model: "gpt-4o", prompt: "rate",
+			want: map[string]*Score{
+				"1": {PostID: "1", Kind: KindScore, Model: "gpt-4o", Prompt: "rate", Score: 7},
+			},
+		},
+		{
+			kind: "elo", model: "llama3", prompt: "rate",
+			want: map[string]*Score{
+				"1": {PostID: "1", Kind: "elo", Model: "llama3", Prompt: "rate", Score: 1516},
+			},
+		},
+		{
+			kind: KindScore, model: "mistral", prompt: "rate",
+			want: map[string]*Score{},
+		},
+	}
+	for _, tt := range tests {
+		got, err := db.Scores(ctx, tt.kind, tt.model, tt.prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s %s: got %+v, want %+v", tt.kind, tt.model, tt.prompt, got, tt.want)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "twai.db")
+
+	// Create a scores table without the data column
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.ExecContext(ctx, `
+		CREATE TABLE scores (
+			post_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			model TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			score REAL NOT NULL,
+			time TIMESTAMP NOT NULL,
+			PRIMARY KEY (post_id, kind, model, prompt)
+		);
+		INSERT INTO scores VALUES ('1', 'score', 'llama3', 'rate', 8, '2024-02-06');`,
+	); err != nil {
+		t.Fatal(err)
+	}
+	_ = old.Close()
+
+	db := openStore(t, path)
+	got, err := db.Scores(ctx, KindScore, "llama3", "rate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*Score{"1": {PostID: "1", Kind: KindScore, Model: "llama3", Prompt: "rate", Score: 8}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// openStore opens the database at the path and closes it after the test.
+func openStore(t *testing.T, path string) *Store {
+	t.Helper()
+	db, err := Open(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
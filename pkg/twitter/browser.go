@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/chromedp/cdproto/network"
@@ -28,6 +29,8 @@ type Browser struct {
 	cookieStore      CookieStore
 	binPath          string
 	headless         bool
+	profiles         map[string]*User
+	profilesLck      sync.Mutex
 }
 
 type BrowserConfig struct {
@@ -53,6 +56,7 @@ func NewBrowser(cfg *BrowserConfig) *Browser {
 		rateLimit:   ratelimit.New(wait),
 		binPath:     cfg.BinPath,
 		headless:    cfg.Headless,
+		profiles:    map[string]*User{},
 	}
 }
 
@@ -0,0 +1,131 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// followerOperations are the GraphQL operations that return users
+var followerOperations = map[string]struct{}{
+	"Followers":             {},
+	"BlueVerifiedFollowers": {},
+	"Following":             {},
+}
+
+// Followers obtains n followers of the given user.
+func (c *Browser) Followers(parent context.Context, handle string, n int) ([]*User, error) {
+	return c.users(parent, handle, "followers", n)
+}
+
+// Following obtains n users followed by the given user.
+func (c *Browser) Following(parent context.Context, handle string, n int) ([]*User, error) {
+	return c.users(parent, handle, "following", n)
+}
+
+func (c *Browser) users(parent context.Context, handle, page string, n int) ([]*User, error) {
+	handle, err := parseUsername(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new tab based on client context
+	ctx, cancel := chromedp.NewContext(c.browserContext)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-parent.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Capture users from GraphQL responses
+	var lck sync.Mutex
+	var captured []*User
+	var decoded bool
+	listen(ctx, followerOperations, func(op string, body []byte) {
+		users, err := ParseUsers(body)
+		if err != nil {
+			log.Printf("twitter: couldn't parse %s response: %v\n", op, err)
+			return
+		}
+		// Responses without users (e.g. cursor only pages) don't disable the
+		// DOM fallback
+		if len(users) == 0 {
+			return
+		}
+		lck.Lock()
+		defer lck.Unlock()
+		decoded = true
+		captured = append(captured, users...)
+	})
+
+	// Navigate to the page
+	u := fmt.Sprintf("https://x.com/%s/%s", handle, page)
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(u),
+		chromedp.WaitVisible(`[data-testid="UserCell"]`, chromedp.ByQuery),
+	); err != nil {
+		return nil, fmt.Errorf("twitter: couldn't navigate to url: %w", err)
+	}
+
+	ids := map[string]struct{}{}
+	var users []*User
+	var noUser int
+	for {
+		select {
+		case <-ctx.Done():
+			return users, nil
+		case <-time.After(2 * time.Second):
+		}
+		// Use GraphQL users if available, fallback to DOM parsing otherwise
+		lck.Lock()
+		candidates, ok := captured, decoded
+		captured = nil
+		lck.Unlock()
+		if !ok {
+			doc, err := getHTML(ctx, "main")
+			if err != nil {
+				return nil, err
+			}
+			candidates = ParseUserCells(doc)
+		}
+		var current int
+		for _, u := range candidates {
+			key := strings.ToLower(u.ID)
+			if _, ok := ids[key]; ok {
+				continue
+			}
+			ids[key] = struct{}{}
+			users = append(users, u)
+			current++
+		}
+
+		// Check if no more users are found
+		if current == 0 {
+			noUser++
+			if noUser > 2 {
+				log.Println("no more users found, reached the end")
+				return users, nil
+			}
+		} else {
+			noUser = 0
+		}
+		if len(users) >= n {
+			return users[:n], nil
+		}
+		log.Printf("user %d/%d\n", len(users), n)
+
+		// Scroll down
+		if err := scrollDown(ctx); err != nil {
+			return nil, fmt.Errorf("twitter: couldn't scroll down: %w", err)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// timelineOperations are the GraphQL operations that return tweets
+var timelineOperations = map[string]struct{}{
+	"HomeTimeline":             {},
+	"HomeLatestTimeline":       {},
+	"UserTweets":               {},
+	"UserTweetsAndReplies":     {},
+	"SearchTimeline":           {},
+	"ListLatestTweetsTimeline": {},
+	"Bookmarks":                {},
+	"Likes":                    {},
+	"TweetDetail":              {},
+}
+
+// capture stores the posts obtained from GraphQL responses.
+type capture struct {
+	lck     sync.Mutex
+	posts   []*Post
+	decoded bool
+}
+
+// listenTimeline listens to the network events of the tab and decodes the
+// GraphQL timeline responses into posts.
+func listenTimeline(ctx context.Context) *capture {
+	c := &capture{}
+	listen(ctx, timelineOperations, func(op string, body []byte) {
+		posts, err := ParseTimeline(body)
+		if err != nil {
+			log.Printf("twitter: couldn't parse %s response: %v\n", op, err)
+			return
+		}
+		// Responses without posts (e.g. cursor only pages) don't disable the
+		// DOM fallback
+		if len(posts) == 0 {
+			return
+		}
+		c.lck.Lock()
+		defer c.lck.Unlock()
+		c.decoded = true
+		c.posts = append(c.posts, posts...)
+	})
+	return c
+}
+
+// listen listens to the network events of the tab and calls fn with the
+// body of the GraphQL responses of the given operations.
+func listen(ctx context.Context, ops map[string]struct{}, fn func(op string, body []byte)) {
+	requests := map[network.RequestID]string{}
+	var lck sync.Mutex
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventResponseReceived:
+			op := graphqlOperation(ev.Response.URL)
+			if _, ok := ops[op]; !ok {
+				return
+			}
+			lck.Lock()
+			requests[ev.RequestID] = op
+			lck.Unlock()
+		case *network.EventLoadingFinished:
+			lck.Lock()
+			op, ok := requests[ev.RequestID]
+			delete(requests, ev.RequestID)
+			lck.Unlock()
+			if !ok {
+				return
+			}
+			// Commands can't be run inside the listener, so use a goroutine
+			go func() {
+				t := chromedp.FromContext(ctx).Target
+				body, err := network.GetResponseBody(ev.RequestID).Do(cdp.WithExecutor(ctx, t))
+				if err != nil {
+					log.Printf("twitter: couldn't get %s response body: %v\n", op, err)
+					return
+				}
+				fn(op, body)
+			}()
+		}
+	})
+}
+
+// take returns the captured posts not included in ids and whether any
+// GraphQL response with posts has been decoded.
+func (c *capture) take(ids map[string]struct{}) ([]*Post, bool) {
+	c.lck.Lock()
+	defer c.lck.Unlock()
+	seen := map[string]struct{}{}
+	var posts []*Post
+	for _, p := range c.posts {
+		if _, ok := ids[p.ID]; ok {
+			continue
+		}
+		if _, ok := seen[p.ID]; ok {
+			continue
+		}
+		seen[p.ID] = struct{}{}
+		posts = append(posts, p)
+	}
+	c.posts = nil
+	return posts, c.decoded
+}
+
+// graphqlOperation returns the operation name of a GraphQL url.
+func graphqlOperation(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if !strings.Contains(u.Path, "/graphql/") {
+		return ""
+	}
+	return path.Base(u.Path)
+}
+
+type gqlTimeline struct {
+	Instructions []struct {
+		Type    string      `json:"type"`
+		Entries []*gqlEntry `json:"entries"`
+		Entry   *gqlEntry   `json:"entry"`
+	} `json:"instructions"`
+}
+
+type gqlEntry struct {
+	EntryID string `json:"entryId"`
+	Content struct {
+		ItemContent *gqlItemContent `json:"itemContent"`
+		Items       []struct {
+			Item struct {
+				ItemContent *gqlItemContent `json:"itemContent"`
+			} `json:"item"`
+		} `json:"items"`
+	} `json:"content"`
+}
+
+type gqlItemContent struct {
+	TweetResults struct {
+		Result *gqlTweet `json:"result"`
+	} `json:"tweet_results"`
+	UserResults struct {
+		Result *gqlUser `json:"result"`
+	} `json:"user_results"`
+
+	entryID string
+}
+
+type gqlTweet struct {
+	Typename string    `json:"__typename"`
+	RestID   string    `json:"rest_id"`
+	Tweet    *gqlTweet `json:"tweet"`
+	Core     struct {
+		UserResults struct {
+			Result *gqlUser `json:"result"`
+		} `json:"user_results"`
+	} `json:"core"`
+	Views struct {
+		Count string `json:"count"`
+	} `json:"views"`
+	NoteTweet struct {
+		NoteTweetResults struct {
+			Result struct {
+				Text string `json:"text"`
+			} `json:"result"`
+		} `json:"note_tweet_results"`
+	} `json:"note_tweet"`
+	QuotedStatus *struct {
+		Result *gqlTweet `json:"result"`
+	} `json:"quoted_status_result"`
+	Legacy struct {
+		FullText         string      `json:"full_text"`
+		CreatedAt        string      `json:"created_at"`
+		ReplyCount       int         `json:"reply_count"`
+		RetweetCount     int         `json:"retweet_count"`
+		FavoriteCount    int         `json:"favorite_count"`
+		InReplyToID      string      `json:"in_reply_to_status_id_str"`
+		QuotedStatusID   string      `json:"quoted_status_id_str"`
+		Entities         gqlEntities `json:"entities"`
+		ExtendedEntities gqlEntities `json:"extended_entities"`
+		RetweetedStatus  *struct {
+			Result *gqlTweet `json:"result"`
+		} `json:"retweeted_status_result"`
+	} `json:"legacy"`
+}
+
+type gqlEntities struct {
+	Hashtags []struct {
+		Text string `json:"text"`
+	} `json:"hashtags"`
+	UserMentions []struct {
+		ScreenName string `json:"screen_name"`
+	} `json:"user_mentions"`
+	URLs []struct {
+		URL         string `json:"url"`
+		ExpandedURL string `json:"expanded_url"`
+	} `json:"urls"`
+	Media []struct {
+		URL           string `json:"url"`
+		Type          string `json:"type"`
+		MediaURLHTTPS string `json:"media_url_https"`
+		ExtAltText    string `json:"ext_alt_text"`
+		VideoInfo     struct {
+			Variants []struct {
+				Bitrate     int    `json:"bitrate"`
+				ContentType string `json:"content_type"`
+				URL         string `json:"url"`
+			} `json:"variants"`
+		} `json:"video_info"`
+	} `json:"media"`
+}
+
+type gqlUser struct {
+	Typename       string `json:"__typename"`
+	RestID         string `json:"rest_id"`
+	IsBlueVerified bool   `json:"is_blue_verified"`
+	Core           struct {
+		ScreenName string `json:"screen_name"`
+		Name       string `json:"name"`
+		CreatedAt  string `json:"created_at"`
+	} `json:"core"`
+	Location struct {
+		Location string `json:"location"`
+	} `json:"location"`
+	Legacy struct {
+		ScreenName     string   `json:"screen_name"`
+		Name           string   `json:"name"`
+		Description    string   `json:"description"`
+		Location       string   `json:"location"`
+		CreatedAt      string   `json:"created_at"`
+		FollowersCount int      `json:"followers_count"`
+		FriendsCount   int      `json:"friends_count"`
+		Verified       bool     `json:"verified"`
+		PinnedTweetIDs []string `json:"pinned_tweet_ids_str"`
+		Entities       struct {
+			URL struct {
+				URLs []struct {
+					ExpandedURL string `json:"expanded_url"`
+				} `json:"urls"`
+			} `json:"url"`
+		} `json:"entities"`
+	} `json:"legacy"`
+}
+
+func toPost(t *gqlTweet) (*Post, error) {
+	t = unwrapTweet(t)
+	if t == nil {
+		return nil, nil
+	}
+	// Retweets are parsed as the original tweet
+	if rt := t.Legacy.RetweetedStatus; rt != nil && unwrapTweet(rt.Result) != nil {
+		p, err := toPost(rt.Result)
+		if err != nil || p == nil {
+			return p, err
+		}
+		if u := t.Core.UserResults.Result; u != nil {
+			p.RetweetedBy = u.screenName()
+		}
+		return p, nil
+	}
+	if t.RestID == "" {
+		return nil, nil
+	}
+	u := t.Core.UserResults.Result
+	if u == nil {
+		return nil, fmt.Errorf("missing user")
+	}
+	created, err := time.Parse(time.RubyDate, t.Legacy.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse time %s: %w", t.Legacy.CreatedAt, err)
+	}
+	var views int
+	if t.Views.Count != "" {
+		views, err = strconv.Atoi(t.Views.Count)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse views %s: %w", t.Views.Count, err)
+		}
+	}
+	p := &Post{
+		ID:            t.RestID,
+		Text:          t.text(),
+		Time:          created.UTC(),
+		UserID:        u.screenName(),
+		UserName:      u.name(),
+		UserFollowers: u.Legacy.FollowersCount,
+		Comments:      t.Legacy.ReplyCount,
+		Retweets:      t.Legacy.RetweetCount,
+		Likes:         t.Legacy.FavoriteCount,
+		Views:         views,
+		ReplyTo:       t.Legacy.InReplyToID,
+		QuotedID:      t.Legacy.QuotedStatusID,
+	}
+
+	// Quoted tweet
+	if q := t.QuotedStatus; q != nil {
+		if qt := unwrapTweet(q.Result); qt != nil {
+			p.QuotedID = qt.RestID
+			p.QuotedText = qt.text()
+		}
+	}
+
+	// Entities
+	for _, h := range t.Legacy.Entities.Hashtags {
+		p.Hashtags = append(p.Hashtags, h.Text)
+	}
+	for _, m := range t.Legacy.Entities.UserMentions {
+		p.Mentions = append(p.Mentions, m.ScreenName)
+	}
+	for _, l := range t.Legacy.Entities.URLs {
+		p.URLs = append(p.URLs, l.ExpandedURL)
+	}
+
+	// Media, extended entities contain all the items of the tweet
+	media := t.Legacy.ExtendedEntities.Media
+	if len(media) == 0 {
+		media = t.Legacy.Entities.Media
+	}
+	for _, m := range media {
+		u := m.MediaURLHTTPS
+		var bitrate int
+		for _, v := range m.VideoInfo.Variants {
+			if v.ContentType == "video/mp4" && v.Bitrate >= bitrate {
+				u = v.URL
+				bitrate = v.Bitrate
+			}
+		}
+		p.Media = append(p.Media, u)
+		p.MediaAlt = append(p.MediaAlt, m.ExtAltText)
+	}
+	return p, nil
+}
+
+// unwrapTweet returns the tweet inside visibility results wrappers.
+func unwrapTweet(t *gqlTweet) *gqlTweet {
+	if t == nil {
+		return nil
+	}
+	if t.Tweet != nil {
+		return t.Tweet
+	}
+	return t
+}
+
+// text returns the full text of the tweet with the short urls expanded and
+// the media urls removed.
+func (t *gqlTweet) text() string {
+	if note := t.NoteTweet.NoteTweetResults.Result.Text; note != "" {
+		return html.UnescapeString(note)
+	}
+	text := t.Legacy.FullText
+	for _, l := range t.Legacy.Entities.URLs {
+		text = strings.ReplaceAll(text, l.URL, l.ExpandedURL)
+	}
+	for _, m := range t.Legacy.Entities.Media {
+		text = strings.ReplaceAll(text, m.URL, "")
+	}
+	for _, m := range t.Legacy.ExtendedEntities.Media {
+		text = strings.ReplaceAll(text, m.URL, "")
+	}
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+func (u *gqlUser) screenName() string {
+	if u.Legacy.ScreenName != "" {
+		return u.Legacy.ScreenName
+	}
+	return u.Core.ScreenName
+}
+
+func (u *gqlUser) name() string {
+	if u.Legacy.Name != "" {
+		return u.Legacy.Name
+	}
+	return u.Core.Name
+}
+
+func (u *gqlUser) toUser() (*User, error) {
+	user := &User{
+		ID:        u.screenName(),
+		Name:      u.name(),
+		Bio:       html.UnescapeString(u.Legacy.Description),
+		Location:  u.Legacy.Location,
+		Followers: u.Legacy.FollowersCount,
+		Following: u.Legacy.FriendsCount,
+		Verified:  u.Legacy.Verified || u.IsBlueVerified,
+	}
+	if user.Location == "" {
+		user.Location = u.Location.Location
+	}
+	if urls := u.Legacy.Entities.URL.URLs; len(urls) > 0 {
+		user.Website = urls[0].ExpandedURL
+	}
+	if len(u.Legacy.PinnedTweetIDs) > 0 {
+		user.PinnedID = u.Legacy.PinnedTweetIDs[0]
+	}
+	created := u.Legacy.CreatedAt
+	if created == "" {
+		created = u.Core.CreatedAt
+	}
+	if created != "" {
+		t, err := time.Parse(time.RubyDate, created)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse time %s: %w", created, err)
+		}
+		user.Joined = t.UTC()
+	}
+	return user, nil
+}
@@ -0,0 +1,421 @@
+package twitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ParseHTML parses the posts of a timeline document.
+func ParseHTML(doc *goquery.Document) []*Post {
+	// Get posts
+	var posts []*Post
+	doc.Find("article").Each(func(i int, s *goquery.Selection) {
+		var p Post
+		// Search time
+		timeNode := s.Find(`time[datetime]`).First()
+		ts, ok := timeNode.Attr("datetime")
+		if !ok {
+			// Skip if no time is found
+			return
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			log.Printf("error parsing time %s: %v\n", ts, err)
+			return
+		}
+		p.Time = t
+
+		// Search ID
+		link, ok := timeNode.Parent().Attr("href")
+		if !ok {
+			return
+		}
+		if link == "" {
+			log.Println("empty link")
+			return
+		}
+		parts := strings.Split(link, "/")
+		p.ID = parts[len(parts)-1]
+
+		// Search user ID
+		p.UserID = strings.TrimPrefix(strings.TrimSpace(s.Find(`div[data-testid="User-Name"] a > div > span`).First().Text()), "@")
+
+		// Search user name
+		p.UserName = strings.TrimSpace(s.Find(`div[data-testid="User-Name"] a`).First().Text())
+
+		// Search post text, the second one belongs to the quoted tweet
+		texts := s.Find(`div[data-testid="tweetText"]`)
+		p.Text = strings.TrimSpace(texts.First().Text())
+		if quoted := s.Find(`div[role="link"] div[data-testid="tweetText"]`); quoted.Length() > 0 {
+			p.QuotedText = strings.TrimSpace(quoted.First().Text())
+			if texts.Length() == 1 {
+				p.Text = ""
+			}
+		}
+
+		// Search retweet marker
+		social := s.Find(`span[data-testid="socialContext"]`).First()
+		if strings.Contains(strings.ToLower(social.Text()), "repost") {
+			if href, ok := social.Closest("a").Attr("href"); ok {
+				p.RetweetedBy = strings.TrimPrefix(href, "/")
+			}
+		}
+
+		// Search entities
+		texts.First().Find("a").Each(func(i int, a *goquery.Selection) {
+			href, _ := a.Attr("href")
+			v := strings.TrimSpace(a.Text())
+			switch {
+			case strings.HasPrefix(href, "/hashtag/"):
+				p.Hashtags = append(p.Hashtags, strings.TrimPrefix(v, "#"))
+			case strings.HasPrefix(v, "@"):
+				p.Mentions = append(p.Mentions, strings.TrimPrefix(v, "@"))
+			case strings.HasPrefix(href, "http"):
+				p.URLs = append(p.URLs, href)
+			}
+		})
+
+		// Search media
+		s.Find(`div[data-testid="tweetPhoto"] img, div[data-testid="videoPlayer"] video`).Each(func(i int, m *goquery.Selection) {
+			// Skip media of the quoted tweet
+			if m.Closest(`div[role="link"]`).Length() > 0 {
+				return
+			}
+			src, ok := m.Attr("src")
+			if !ok || strings.HasPrefix(src, "blob:") {
+				src, _ = m.Attr("poster")
+			}
+			alt, _ := m.Attr("alt")
+			p.Media = append(p.Media, src)
+			p.MediaAlt = append(p.MediaAlt, alt)
+		})
+
+		// Search stats (comments, retweets, likes, views)
+		s.Find(`span[data-testid="app-text-transition-container"]`).Each(func(i int, s *goquery.Selection) {
+			v := s.Text()
+			n, err := ParseNumber(v)
+			if err != nil {
+				log.Printf("error parsing number: %v\n", err)
+				return
+			}
+			switch i {
+			case 0:
+				p.Comments = n
+			case 1:
+				p.Retweets = n
+			case 2:
+				p.Likes = n
+			case 3:
+				p.Views = n
+			}
+		})
+		if p.ID == "" || p.UserID != "" || p.Text != "" {
+			posts = append(posts, &p)
+		}
+	})
+	return posts
+}
+
+// ParseUser decodes the user of a captured GraphQL user response.
+func ParseUser(data []byte) (*User, error) {
+	var resp struct {
+		Data struct {
+			User struct {
+				Result *gqlUser `json:"result"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("twitter: couldn't unmarshal user: %w", err)
+	}
+	u := resp.Data.User.Result
+	if u == nil || u.screenName() == "" {
+		return nil, fmt.Errorf("twitter: user not found")
+	}
+	return u.toUser()
+}
+
+// ParseUserCells parses the users of a users list document (e.g. followers or
+// following).
+// Follower counts aren't displayed in user cells, so they are left empty.
+func ParseUserCells(doc *goquery.Document) []*User {
+	var users []*User
+	doc.Find(`[data-testid="UserCell"]`).Each(func(i int, s *goquery.Selection) {
+		var u User
+		s.Find(`a[role="link"] span`).EachWithBreak(func(i int, span *goquery.Selection) bool {
+			v := strings.TrimSpace(span.Text())
+			switch {
+			case strings.HasPrefix(v, "@"):
+				u.ID = strings.TrimPrefix(v, "@")
+				return false
+			case u.Name == "" && v != "":
+				u.Name = v
+			}
+			return true
+		})
+		if u.ID == "" {
+			return
+		}
+		// The bio is the only text block outside the links
+		s.Find(`div[dir="auto"]`).Each(func(i int, d *goquery.Selection) {
+			if d.Closest(`a[role="link"]`).Length() > 0 {
+				return
+			}
+			if v := strings.TrimSpace(d.Text()); v != "" {
+				u.Bio = v
+			}
+		})
+		u.Verified = s.Find(`svg[data-testid="icon-verified"]`).Length() > 0
+		users = append(users, &u)
+	})
+	return users
+}
+
+// ParseProfile parses the user of a profile document.
+func ParseProfile(doc *goquery.Document, handle string) (*User, error) {
+	header := doc.Find(`div[data-testid="UserName"]`).First()
+	if header.Length() == 0 {
+		return nil, fmt.Errorf("twitter: profile %s not found", handle)
+	}
+	u := &User{
+		ID:       handle,
+		Name:     strings.TrimSpace(header.Find("span").First().Text()),
+		Bio:      strings.TrimSpace(doc.Find(`div[data-testid="UserDescription"]`).First().Text()),
+		Location: strings.TrimSpace(doc.Find(`span[data-testid="UserLocation"]`).First().Text()),
+		Verified: header.Find(`svg[data-testid="icon-verified"]`).Length() > 0,
+	}
+
+	// Website href is a short url, so use the displayed url if available
+	if a := doc.Find(`a[data-testid="UserUrl"]`).First(); a.Length() > 0 {
+		u.Website = strings.TrimSpace(a.Text())
+		if href, ok := a.Attr("href"); ok && u.Website == "" {
+			u.Website = href
+		}
+	}
+
+	// Join date (e.g. Joined March 2010)
+	if joined := strings.TrimSpace(doc.Find(`span[data-testid="UserJoinDate"]`).First().Text()); joined != "" {
+		joined = strings.TrimSpace(strings.TrimPrefix(joined, "Joined"))
+		t, err := time.Parse("January 2006", joined)
+		if err != nil {
+			log.Printf("error parsing join date %s: %v\n", joined, err)
+		} else {
+			u.Joined = t
+		}
+	}
+
+	// Stats
+	var err error
+	followers := doc.Find(fmt.Sprintf(`a[href="/%s/verified_followers"] span, a[href="/%s/followers"] span`, handle, handle)).First().Text()
+	if u.Followers, err = ParseNumber(followers); err != nil {
+		log.Printf("error parsing followers: %v\n", err)
+	}
+	following := doc.Find(fmt.Sprintf(`a[href="/%s/following"] span`, handle)).First().Text()
+	if u.Following, err = ParseNumber(following); err != nil {
+		log.Printf("error parsing following: %v\n", err)
+	}
+
+	// Pinned tweet
+	doc.Find("article").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		social := s.Find(`div[data-testid="socialContext"], span[data-testid="socialContext"]`).First()
+		if !strings.Contains(strings.ToLower(social.Text()), "pinned") {
+			return true
+		}
+		if link, ok := s.Find(`time[datetime]`).First().Parent().Attr("href"); ok {
+			parts := strings.Split(link, "/")
+			u.PinnedID = parts[len(parts)-1]
+		}
+		return false
+	})
+	return u, nil
+}
+
+// ParseTimeline decodes the posts of a captured GraphQL timeline response.
+func ParseTimeline(data []byte) ([]*Post, error) {
+	contents, err := timelineContents(data)
+	if err != nil {
+		return nil, err
+	}
+	var posts []*Post
+	for _, content := range contents {
+		p, err := toPost(content.TweetResults.Result)
+		if err != nil {
+			log.Printf("twitter: couldn't parse tweet %s: %v\n", content.entryID, err)
+			continue
+		}
+		if p != nil {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+// ParseUsers decodes the users of a captured GraphQL users timeline response
+// (e.g. followers or following).
+func ParseUsers(data []byte) ([]*User, error) {
+	contents, err := timelineContents(data)
+	if err != nil {
+		return nil, err
+	}
+	var users []*User
+	for _, content := range contents {
+		gu := content.UserResults.Result
+		if gu == nil || gu.screenName() == "" {
+			continue
+		}
+		u, err := gu.toUser()
+		if err != nil {
+			log.Printf("twitter: couldn't parse user %s: %v\n", content.entryID, err)
+			continue
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// timelineContents returns the item contents of a GraphQL timeline response.
+func timelineContents(data []byte) ([]*gqlItemContent, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("twitter: couldn't unmarshal timeline: %w", err)
+	}
+	// The path to the instructions depends on the operation, so search for them
+	timelines := findKey(raw, "instructions")
+	var contents []*gqlItemContent
+	for _, instructions := range timelines {
+		js, err := json.Marshal(map[string]any{"instructions": instructions})
+		if err != nil {
+			return nil, fmt.Errorf("twitter: couldn't marshal instructions: %w", err)
+		}
+		var timeline gqlTimeline
+		if err := json.Unmarshal(js, &timeline); err != nil {
+			return nil, fmt.Errorf("twitter: couldn't unmarshal instructions: %w", err)
+		}
+		for _, inst := range timeline.Instructions {
+			entries := inst.Entries
+			if inst.Entry != nil {
+				entries = append(entries, inst.Entry)
+			}
+			for _, entry := range entries {
+				if c := entry.Content.ItemContent; c != nil {
+					c.entryID = entry.EntryID
+					contents = append(contents, c)
+				}
+				for _, item := range entry.Content.Items {
+					if c := item.Item.ItemContent; c != nil {
+						c.entryID = entry.EntryID
+						contents = append(contents, c)
+					}
+				}
+			}
+		}
+	}
+	return contents, nil
+}
+
+// findKey returns the values of all the keys with the given name.
+func findKey(v any, key string) []any {
+	var values []any
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if k == key {
+				values = append(values, child)
+				continue
+			}
+			values = append(values, findKey(child, key)...)
+		}
+	case []any:
+		for _, child := range v {
+			values = append(values, findKey(child, key)...)
+		}
+	}
+	return values
+}
+
+// setDepths sets the reply depth of the posts of a conversation.
+// The focal post and the self-thread of its author have depth 0, replies have
+// the depth of their parent plus one.
+func setDepths(posts []*Post, focalID, author string) {
+	byID := map[string]*Post{}
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+	depths := map[string]int{}
+	var depth func(p *Post) int
+	depth = func(p *Post) int {
+		if d, ok := depths[p.ID]; ok {
+			return d
+		}
+		// Mark as visited to avoid loops
+		depths[p.ID] = 0
+		isAuthor := strings.EqualFold(p.UserID, author)
+		var d int
+		parent, ok := byID[p.ReplyTo]
+		switch {
+		case p.ID == focalID:
+			d = 0
+		case !ok && isAuthor:
+			d = 0
+		case !ok:
+			// Parent unknown (e.g. DOM fallback), assume a direct reply
+			d = 1
+		default:
+			d = depth(parent)
+			// Author replies to its own thread are part of the thread
+			if d > 0 || !isAuthor {
+				d++
+			}
+		}
+		depths[p.ID] = d
+		return d
+	}
+	for _, p := range posts {
+		p.Depth = depth(p)
+	}
+}
+
+// ParseNumber parses a number as displayed by twitter (e.g. 1,234 or 1.2K).
+func ParseNumber(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.ReplaceAll(s, ",", "")
+	mul := 1
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mul = 1000
+	case strings.HasSuffix(s, "M"):
+		mul = 1000000
+	case strings.HasSuffix(s, "B"):
+		mul = 1000000000
+	}
+	var views int
+	if v := decimalRegex.FindString(s); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing views %s: %w", s, err)
+		}
+		views = int(f * float64(mul))
+	} else if v := numberRegex.FindString(s); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing views %s: %w", s, err)
+		}
+		views = n * mul
+	} else {
+		return 0, fmt.Errorf("error parsing views %s", s)
+	}
+	return views, nil
+}
+
+var numberRegex = regexp.MustCompile(`\d+`)
+var decimalRegex = regexp.MustCompile(`\d+.\d+`)
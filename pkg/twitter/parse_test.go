@@ -0,0 +1,525 @@
+package twitter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		file string
+		want []*Post
+	}{
+		{
+			file: "timeline.html",
+			want: []*Post{
+				{
+					ID:       "1790000000000000001",
+					Text:     "Go 1.22.3 is released! #golang thanks @rob_pike go.dev/doc/devel/rel…",
+					Time:     date("2024-05-15T10:00:00Z"),
+					UserID:   "golang",
+					UserName: "Go",
+					Comments: 12,
+					Retweets: 150,
+					Likes:    1200,
+					Views:    45000,
+					Media:    List{"https://pbs.twimg.com/media/GNgo1?format=jpg&name=small"},
+					MediaAlt: List{"Go gopher celebrating"},
+					Hashtags: List{"golang"},
+					Mentions: List{"rob_pike"},
+					URLs:     List{"https://t.co/abc123"},
+				},
+				{
+					ID:          "1790000000000000021",
+					Text:        "Go 1.22.3 is released!",
+					Time:        date("2024-05-15T10:00:00Z"),
+					UserID:      "golang",
+					UserName:    "Go",
+					Comments:    12,
+					Retweets:    150,
+					Likes:       900,
+					Views:       45000,
+					RetweetedBy: "rob_pike",
+				},
+				{
+					ID:         "1790000000000000030",
+					Text:       "This is great news",
+					Time:       date("2024-05-15T13:00:00Z"),
+					UserID:     "rob_pike",
+					UserName:   "Rob Pike",
+					Comments:   2,
+					Retweets:   3,
+					Likes:      40,
+					Views:      2000,
+					QuotedText: "Go 1.22.3 is released!",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := ParseHTML(readDocument(t, tt.file))
+			comparePosts(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseTimeline(t *testing.T) {
+	golang := func(p Post) *Post {
+		p.UserID = "golang"
+		p.UserName = "Go"
+		p.UserFollowers = 250000
+		return &p
+	}
+	rob := func(p Post) *Post {
+		p.UserID = "rob_pike"
+		p.UserName = "Rob Pike"
+		p.UserFollowers = 120000
+		return &p
+	}
+	tests := []struct {
+		file string
+		want []*Post
+	}{
+		{
+			file: "home_timeline.json",
+			want: []*Post{
+				golang(Post{
+					ID:       "1790000000000000001",
+					Text:     "Go 1.22.3 is released! #golang https://go.dev/doc/devel/release",
+					Time:     date("2024-05-15T10:00:00Z"),
+					Comments: 12,
+					Retweets: 150,
+					Likes:    900,
+					Views:    45000,
+					Media:    List{"https://pbs.twimg.com/media/GNgo1.jpg"},
+					MediaAlt: List{"Go gopher celebrating"},
+					Hashtags: List{"golang"},
+					URLs:     List{"https://go.dev/doc/devel/release"},
+				}),
+				rob(Post{
+					ID:       "1790000000000000002",
+					Text:     "Tips & tricks for @golang users",
+					Time:     date("2024-05-15T09:30:00Z"),
+					Comments: 1,
+					Retweets: 2,
+					Likes:    3,
+					Mentions: List{"golang"},
+				}),
+			},
+		},
+		{
+			file: "user_tweets.json",
+			want: []*Post{
+				golang(Post{
+					ID:       "1700000000000000001",
+					Text:     "Welcome to the official Go account",
+					Time:     date("2023-09-08T12:00:00Z"),
+					Comments: 5,
+					Retweets: 40,
+					Likes:    300,
+					Views:    90000,
+				}),
+				golang(Post{
+					ID:       "1790000000000000003",
+					Text:     "Watch the talk",
+					Time:     date("2024-05-16T08:00:00Z"),
+					Comments: 3,
+					Retweets: 20,
+					Likes:    100,
+					Views:    8000,
+					Media:    List{"https://video.twimg.com/ext_tw_video/1/pu/vid/640x360/high.mp4"},
+					MediaAlt: List{""},
+				}),
+				golang(Post{
+					ID:       "1790000000000000004",
+					Text:     "A thread about modules 1/2",
+					Time:     date("2024-05-16T09:00:00Z"),
+					Comments: 1,
+					Retweets: 1,
+					Likes:    10,
+					Views:    500,
+				}),
+				golang(Post{
+					ID:      "1790000000000000005",
+					Text:    "Modules are great 2/2",
+					Time:    date("2024-05-16T09:01:00Z"),
+					Likes:   5,
+					Views:   400,
+					ReplyTo: "1790000000000000004",
+				}),
+			},
+		},
+		{
+			file: "search_timeline.json",
+			want: []*Post{
+				{
+					ID:            "1790000000000000006",
+					Text:          "Generics in #golang are great",
+					Time:          date("2024-05-15T11:00:00Z"),
+					UserID:        "gopher",
+					UserName:      "Gopher",
+					UserFollowers: 42,
+					Retweets:      1,
+					Likes:         7,
+					Views:         120,
+					Hashtags:      List{"golang"},
+				},
+			},
+		},
+		{
+			file: "tweet_detail.json",
+			want: []*Post{
+				golang(Post{
+					ID:       "1790000000000000010",
+					Text:     "What's your favourite Go feature?",
+					Time:     date("2024-05-17T10:00:00Z"),
+					Comments: 2,
+					Retweets: 5,
+					Likes:    50,
+					Views:    3000,
+				}),
+				rob(Post{
+					ID:       "1790000000000000011",
+					Text:     "@golang Goroutines",
+					Time:     date("2024-05-17T10:05:00Z"),
+					Likes:    20,
+					Views:    800,
+					ReplyTo:  "1790000000000000010",
+					Mentions: List{"golang"},
+				}),
+				golang(Post{
+					ID:       "1790000000000000012",
+					Text:     "@rob_pike Same here",
+					Time:     date("2024-05-17T10:10:00Z"),
+					Likes:    8,
+					Views:    300,
+					ReplyTo:  "1790000000000000011",
+					Mentions: List{"rob_pike"},
+				}),
+			},
+		},
+		{
+			file: "retweet.json",
+			want: []*Post{
+				golang(Post{
+					ID:          "1790000000000000021",
+					Text:        "Go 1.22.3 is released!",
+					Time:        date("2024-05-15T10:00:00Z"),
+					Comments:    12,
+					Retweets:    150,
+					Likes:       900,
+					Views:       45000,
+					RetweetedBy: "rob_pike",
+				}),
+			},
+		},
+		{
+			file: "quote.json",
+			want: []*Post{
+				rob(Post{
+					ID:         "1790000000000000030",
+					Text:       "This is great news https://twitter.com/golang/status/1790000000000000021",
+					Time:       date("2024-05-15T13:00:00Z"),
+					Comments:   2,
+					Retweets:   3,
+					Likes:      40,
+					Views:      2000,
+					QuotedID:   "1790000000000000021",
+					QuotedText: "Go 1.22.3 is released!",
+					URLs:       List{"https://twitter.com/golang/status/1790000000000000021"},
+				}),
+			},
+		},
+		{
+			file: "note_tweet.json",
+			want: []*Post{
+				rob(Post{
+					ID:       "1790000000000000040",
+					Text:     "A long post about Go error handling & why it matters.\n\nErrors are values, so handle them like any other value.",
+					Time:     date("2024-05-15T14:00:00Z"),
+					Comments: 4,
+					Retweets: 30,
+					Likes:    210,
+					Views:    9000,
+				}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := ParseTimeline(readFixture(t, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			comparePosts(t, got, tt.want)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := ParseTimeline([]byte("<html>")); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestParseUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *User
+		wantErr bool
+	}{
+		{
+			name: "user_by_screen_name.json",
+			data: readFixture(t, "user_by_screen_name.json"),
+			want: &User{
+				ID:        "golang",
+				Name:      "Go",
+				Bio:       "The Go programming language & its community",
+				Location:  "Mountain View, CA",
+				Website:   "https://go.dev",
+				Joined:    date("2009-01-29T18:35:54Z"),
+				Followers: 250000,
+				Following: 5,
+				Verified:  true,
+				PinnedID:  "1700000000000000001",
+			},
+		},
+		{
+			name:    "not found",
+			data:    []byte(`{"data":{"user":{}}}`),
+			wantErr: true,
+		},
+		{
+			name:    "invalid",
+			data:    []byte("<html>"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUser(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUsers(t *testing.T) {
+	tests := []struct {
+		file string
+		want []*User
+	}{
+		{
+			file: "followers.json",
+			want: []*User{
+				{
+					ID:        "rob_pike",
+					Name:      "Rob Pike",
+					Joined:    date("2009-01-29T18:35:54Z"),
+					Followers: 120000,
+					Following: 10,
+				},
+				{
+					ID:        "gopher",
+					Name:      "Gopher",
+					Bio:       "Learning Go",
+					Joined:    date("2009-01-29T18:35:54Z"),
+					Followers: 42,
+					Following: 10,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := ParseUsers(readFixture(t, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareUsers(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		file    string
+		handle  string
+		want    *User
+		wantErr bool
+	}{
+		{
+			file:   "profile.html",
+			handle: "golang",
+			want: &User{
+				ID:        "golang",
+				Name:      "Go",
+				Bio:       "The Go programming language & its community",
+				Location:  "Mountain View, CA",
+				Website:   "go.dev",
+				Joined:    date("2009-01-01T00:00:00Z"),
+				Followers: 250300,
+				Following: 5,
+				Verified:  true,
+				PinnedID:  "1700000000000000001",
+			},
+		},
+		{
+			file:    "timeline.html",
+			handle:  "golang",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := ParseProfile(readDocument(t, tt.file), tt.handle)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserCells(t *testing.T) {
+	tests := []struct {
+		file string
+		want []*User
+	}{
+		{
+			file: "user_cells.html",
+			want: []*User{
+				{ID: "rob_pike", Name: "Rob Pike", Bio: "Co-creator of Go", Verified: true},
+				{ID: "gopher", Name: "Gopher"},
+			},
+		},
+		{
+			file: "timeline.html",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := ParseUserCells(readDocument(t, tt.file))
+			compareUsers(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "7", want: 7},
+		{in: "12", want: 12},
+		{in: "999", want: 999},
+		{in: "1,234", want: 1234},
+		{in: " 56 ", want: 56},
+		{in: "45K", want: 45000},
+		{in: "1.2K", want: 1200},
+		{in: "4.35K", want: 4350},
+		{in: "3.4M", want: 3400000},
+		{in: "12M", want: 12000000},
+		{in: "1B", want: 1000000000},
+		{in: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseNumber(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func comparePosts(t *testing.T, got, want []*Post) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d posts, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("post %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func compareUsers(t *testing.T, got, want []*User) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d users, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("user %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func readDocument(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
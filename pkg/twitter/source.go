@@ -0,0 +1,121 @@
+package twitter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// source is a timeline that can be scraped.
+type source struct {
+	// url is the page to navigate to
+	url string
+	// wait is the selector to wait for before scraping
+	wait string
+}
+
+const (
+	articleSelector = "article time[datetime]"
+	listSelector    = `div[data-testid="primaryColumn"] section article time[datetime]`
+)
+
+var usernameRegex = regexp.MustCompile(`^@?[A-Za-z0-9_]{1,15}$`)
+var statusRegex = regexp.MustCompile(`^(?:https?://)?(?:www\.|mobile\.)?(?:x|twitter)\.com/([A-Za-z0-9_]{1,15})/status/(\d+)`)
+
+// parseSource converts a page string to a source.
+// Supported values are:
+//   - home: the home timeline
+//   - bookmarks: the bookmarks of the logged user
+//   - bookmarks:<id>: the bookmarks of a folder of the logged user
+//   - list:<id>: the tweets of a list
+//   - likes:<user>: the tweets liked by a user
+//   - <user>: the tweets of a user
+//   - <path>: any other page path (e.g. <user>/with_replies)
+func parseSource(page string) (*source, error) {
+	page = strings.TrimSpace(page)
+	kind, value, ok := strings.Cut(page, ":")
+	if !ok {
+		kind, value = "", page
+	}
+	value = strings.TrimSpace(value)
+	switch kind {
+	case "":
+		switch value {
+		case "", "home":
+			return &source{url: "https://x.com/home", wait: articleSelector}, nil
+		case "bookmarks":
+			return &source{url: "https://x.com/i/bookmarks", wait: articleSelector}, nil
+		}
+		// Paths are used as they are
+		if strings.Contains(value, "/") {
+			return &source{url: fmt.Sprintf("https://x.com/%s", strings.TrimPrefix(value, "/")), wait: articleSelector}, nil
+		}
+		user, err := parseUsername(value)
+		if err != nil {
+			return nil, err
+		}
+		return &source{url: fmt.Sprintf("https://x.com/%s", user), wait: articleSelector}, nil
+	case "bookmarks":
+		if value == "" || strings.Trim(value, "0123456789") != "" {
+			return nil, fmt.Errorf("twitter: invalid bookmarks folder id %q", value)
+		}
+		return &source{url: fmt.Sprintf("https://x.com/i/bookmarks/%s", value), wait: articleSelector}, nil
+	case "list":
+		if value == "" || strings.Trim(value, "0123456789") != "" {
+			return nil, fmt.Errorf("twitter: invalid list id %q", value)
+		}
+		return &source{url: fmt.Sprintf("https://x.com/i/lists/%s", value), wait: listSelector}, nil
+	case "likes":
+		user, err := parseUsername(value)
+		if err != nil {
+			return nil, err
+		}
+		return &source{url: fmt.Sprintf("https://x.com/%s/likes", user), wait: articleSelector}, nil
+	default:
+		return nil, fmt.Errorf("twitter: unknown page type %q", kind)
+	}
+}
+
+// searchSource returns the source for the given search query and tab.
+func searchSource(query, tab string) (*source, error) {
+	if query == "" {
+		return nil, fmt.Errorf("twitter: search query is empty")
+	}
+	values := url.Values{}
+	values.Set("q", query)
+	values.Set("src", "typed_query")
+	switch tab {
+	case "", SearchLatest:
+		values.Set("f", "live")
+	case SearchTop:
+		values.Set("f", "top")
+	default:
+		return nil, fmt.Errorf("twitter: invalid search tab %q", tab)
+	}
+	return &source{
+		url:  fmt.Sprintf("https://x.com/search?%s", values.Encode()),
+		wait: articleSelector,
+	}, nil
+}
+
+// statusSource returns the source of a status url along with the author and
+// the id of the tweet.
+func statusSource(rawURL string) (*source, string, string, error) {
+	m := statusRegex.FindStringSubmatch(strings.TrimSpace(rawURL))
+	if m == nil {
+		return nil, "", "", fmt.Errorf("twitter: invalid status url %q", rawURL)
+	}
+	user, id := m[1], m[2]
+	return &source{
+		url:  fmt.Sprintf("https://x.com/%s/status/%s", user, id),
+		wait: articleSelector,
+	}, user, id, nil
+}
+
+func parseUsername(v string) (string, error) {
+	if !usernameRegex.MatchString(v) {
+		return "", fmt.Errorf("twitter: invalid username %q", v)
+	}
+	return strings.TrimPrefix(v, "@"), nil
+}
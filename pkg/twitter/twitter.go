@@ -2,15 +2,13 @@ package twitter
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/chromedp/cdproto/input"
 	"github.com/chromedp/chromedp"
 )
 
@@ -25,9 +23,113 @@ type Post struct {
 	Retweets      int       `json:"retweets"`
 	Likes         int       `json:"likes"`
 	Views         int       `json:"views"`
+
+	Media       List   `json:"media,omitempty"`
+	MediaAlt    List   `json:"media_alt,omitempty"`
+	QuotedID    string `json:"quoted_id,omitempty"`
+	QuotedText  string `json:"quoted_text,omitempty"`
+	RetweetedBy string `json:"retweeted_by,omitempty"`
+	ReplyTo     string `json:"reply_to,omitempty"`
+	Hashtags    List   `json:"hashtags,omitempty"`
+	Mentions    List   `json:"mentions,omitempty"`
+	URLs        List   `json:"urls,omitempty"`
+
+	// Depth is the reply depth in a conversation, 0 for the author's thread
+	Depth int `json:"depth,omitempty"`
+}
+
+// List is a list of strings that is stored as a JSON array in CSV files.
+type List []string
+
+// MarshalCSV implements gocsv.TypeMarshaller.
+func (l List) MarshalCSV() (string, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal([]string(l))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// UnmarshalCSV implements gocsv.TypeUnmarshaller.
+func (l *List) UnmarshalCSV(s string) error {
+	if s == "" {
+		*l = nil
+		return nil
+	}
+	var v []string
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// PostsConfig configures which posts are obtained.
+type PostsConfig struct {
+	// N is the maximum number of posts to obtain
+	N int
+	// Followers obtains the followers of the authors
+	Followers bool
+	// SinceID stops scrolling when only posts older than this id are found
+	SinceID string
+	// Since stops scrolling when only posts older than this time are found
+	Since time.Time
+	// Until skips posts newer than this time
+	Until time.Time
+	// OnPost is called with each post as soon as it is obtained
+	OnPost func(*Post)
+	// OnComplete is called if all the posts newer than since id and since
+	// were obtained, because only older posts or no more posts were found
+	OnComplete func()
+}
+
+// Posts obtains posts from the given page.
+// The page can be home, bookmarks, bookmarks:<id>, list:<id>, likes:<user>,
+// a username or a page path.
+func (c *Browser) Posts(parent context.Context, page string, cfg *PostsConfig) ([]*Post, error) {
+	src, err := parseSource(page)
+	if err != nil {
+		return nil, err
+	}
+	return c.posts(parent, src, cfg)
+}
+
+// Search tabs
+const (
+	SearchLatest = "latest"
+	SearchTop    = "top"
+)
+
+// Search obtains posts from the search results of the given query.
+// The tab can be latest or top.
+func (c *Browser) Search(parent context.Context, query, tab string, cfg *PostsConfig) ([]*Post, error) {
+	src, err := searchSource(query, tab)
+	if err != nil {
+		return nil, err
+	}
+	return c.posts(parent, src, cfg)
+}
+
+// Conversation obtains the thread of the author of the given status url
+// along with up to n posts in total including the replies.
+func (c *Browser) Conversation(parent context.Context, rawURL string, n int) ([]*Post, error) {
+	src, author, id, err := statusSource(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := c.posts(parent, src, &PostsConfig{N: n})
+	if err != nil {
+		return nil, err
+	}
+	setDepths(posts, id, author)
+	return posts, nil
 }
 
-func (c *Browser) Posts(parent context.Context, page string, n int, withFollowers bool) ([]*Post, error) {
+func (c *Browser) posts(parent context.Context, src *source, cfg *PostsConfig) ([]*Post, error) {
+	n := cfg.N
 	// Create a new tab based on client context
 	ctx, cancel := chromedp.NewContext(c.browserContext)
 	defer cancel()
@@ -40,15 +142,12 @@ func (c *Browser) Posts(parent context.Context, page string, n int, withFollower
 		}
 	}()
 
-	// Use home page if no page is provided
-	if page == "" {
-		page = "home"
-	}
+	// Capture posts from GraphQL responses
+	capture := listenTimeline(ctx)
 
 	// Navigate to the page
-	u := fmt.Sprintf("https://x.com/%s", page)
 	if err := chromedp.Run(ctx,
-		chromedp.Navigate(u),
+		chromedp.Navigate(src.url),
 		chromedp.WaitVisible("body", chromedp.ByQuery),
 	); err != nil {
 		return nil, fmt.Errorf("twitter: couldn't navigate to url: %w", err)
@@ -56,14 +155,13 @@ func (c *Browser) Posts(parent context.Context, page string, n int, withFollower
 
 	// Wait for the posts to load
 	if err := chromedp.Run(ctx,
-		chromedp.WaitVisible("article time[datetime]", chromedp.ByQuery),
+		chromedp.WaitVisible(src.wait, chromedp.ByQuery),
 	); err != nil {
 		return nil, fmt.Errorf("twitter: couldn't wait for posts: %w", err)
 	}
 
 	ids := map[string]struct{}{}
 	var posts []*Post
-	followers := map[string]int{}
 	var noPost int
 	for {
 		select {
@@ -71,28 +169,52 @@ func (c *Browser) Posts(parent context.Context, page string, n int, withFollower
 			return posts, nil
 		case <-time.After(2 * time.Second):
 		}
-		currentPosts, err := getPosts(ctx, ids)
-		if err != nil {
-			return nil, fmt.Errorf("twitter: couldn't get posts: %w", err)
+		// Use GraphQL posts if available, fallback to DOM parsing otherwise
+		currentPosts, decoded := capture.take(ids)
+		if !decoded {
+			candidate, err := getPosts(ctx, ids)
+			if err != nil {
+				return nil, fmt.Errorf("twitter: couldn't get posts: %w", err)
+			}
+			currentPosts = candidate
 		}
+		var newer, older int
 		for _, post := range currentPosts {
 			ids[post.ID] = struct{}{}
 
+			// Skip posts out of the time range, posts newer than until are
+			// skipped but still count as newer than the watermark
+			if cfg.isOlder(post) {
+				older++
+				continue
+			}
+			newer++
+			if !cfg.Until.IsZero() && post.Time.After(cfg.Until) {
+				continue
+			}
+
 			// Obtain the user stats
-			if withFollowers {
-				f, ok := followers[post.UserID]
-				if !ok {
-					candidate, err := getFolowers(ctx, post.UserID)
-					if err != nil {
-						return nil, fmt.Errorf("twitter: couldn't get followers: %w", err)
-					}
-					f = candidate
+			if cfg.Followers && post.UserFollowers == 0 {
+				u, err := c.Profile(parent, post.UserID)
+				if err != nil {
+					return nil, fmt.Errorf("twitter: couldn't get followers: %w", err)
 				}
-				post.UserFollowers = f
+				post.UserFollowers = u.Followers
 			}
 
 			// Append the post
 			posts = append(posts, post)
+			if cfg.OnPost != nil && len(posts) <= n {
+				cfg.OnPost(post)
+			}
+		}
+
+		// Check if the watermark has been reached, pinned posts may be older
+		// so only stop when all the posts of the batch are older
+		if older > 0 && newer == 0 {
+			log.Println("only older posts found, reached the watermark")
+			cfg.complete()
+			return posts, nil
 		}
 
 		// Check if no more posts are found
@@ -100,6 +222,7 @@ func (c *Browser) Posts(parent context.Context, page string, n int, withFollower
 			noPost++
 			if noPost > 2 {
 				log.Println("no more posts found, reached the end")
+				cfg.complete()
 				return posts, nil
 			}
 		} else {
@@ -117,60 +240,33 @@ func (c *Browser) Posts(parent context.Context, page string, n int, withFollower
 	}
 }
 
-func getFolowers(ctx context.Context, userID string) (int, error) {
-	// Scroll to the user
-	avatar := fmt.Sprintf(`div[data-testid="User-Name"] a[href="/%s"]`, userID)
-	if err := chromedp.Run(ctx, chromedp.ScrollIntoView(avatar)); err != nil {
-		return 0, fmt.Errorf("twitter: couldn't scroll into view: %w", err)
-	}
-
-	// Move the mouse to the user
-	if err := mouseMove(ctx, avatar); err != nil {
-		return 0, err
-	}
-
-	// Wait for the user stats to appear
-	stats := fmt.Sprintf(`div[data-testid="HoverCard"] a[href="/%s/verified_followers"] span`, userID)
-	if err := chromedp.Run(ctx, chromedp.WaitVisible(stats, chromedp.ByQuery)); err != nil {
-		return 0, fmt.Errorf("twitter: couldn't wait for user stats: %w", err)
-	}
-
-	// Load the document
-	doc, err := getHTML(ctx, "body")
-	if err != nil {
-		return 0, err
+func (cfg *PostsConfig) complete() {
+	if cfg.OnComplete != nil {
+		cfg.OnComplete()
 	}
+}
 
-	// Get the followers
-	v := doc.Find(stats).First().Text()
-	followers, err := toNumber(v)
-	if err != nil {
-		log.Printf("error parsing followers: %v\n", err)
+// isOlder returns whether the post is older than the since id or time.
+func (cfg *PostsConfig) isOlder(p *Post) bool {
+	if cfg.SinceID != "" && CompareIDs(p.ID, cfg.SinceID) <= 0 {
+		return true
 	}
-
-	// Move the mouse up
-	if err := chromedp.Run(ctx, chromedp.MouseEvent(input.MouseMoved, 10, 10)); err != nil {
-		return 0, fmt.Errorf("twitter: couldn't move mouse: %w", err)
+	if !cfg.Since.IsZero() && p.Time.Before(cfg.Since) {
+		return true
 	}
+	return false
+}
 
-	// Wait for the user stats to disappear
-	for {
-		var isNotVisible bool
-		if err := chromedp.Run(ctx,
-			chromedp.Evaluate(`document.querySelector('`+stats+`') === null || document.querySelector('`+stats+`').offsetParent === null`, &isNotVisible),
-		); err != nil {
-			return 0, fmt.Errorf("twitter: couldn't check if stats are not visible: %w", err)
-		}
-		if isNotVisible {
-			break
-		}
-		select {
-		case <-ctx.Done():
-			return 0, nil
-		case <-time.After(100 * time.Millisecond):
+// CompareIDs compares two tweet ids.
+// Tweet ids are snowflake ids, so newer ids are greater.
+func CompareIDs(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
 		}
+		return 1
 	}
-	return followers, nil
+	return strings.Compare(a, b)
 }
 
 func getPosts(ctx context.Context, ids map[string]struct{}) ([]*Post, error) {
@@ -180,114 +276,17 @@ func getPosts(ctx context.Context, ids map[string]struct{}) ([]*Post, error) {
 		return nil, err
 	}
 
-	// Get posts
+	// Parse posts and skip the ones already obtained
 	var posts []*Post
-	doc.Find("article").Each(func(i int, s *goquery.Selection) {
-		var p Post
-		// Search time
-		timeNode := s.Find(`time[datetime]`).First()
-		ts, ok := timeNode.Attr("datetime")
-		if !ok {
-			// Skip if no time is found
-			return
-		}
-		t, err := time.Parse(time.RFC3339, ts)
-		if err != nil {
-			log.Printf("error parsing time %s: %v\n", ts, err)
-			return
-		}
-		p.Time = t
-
-		// Search ID
-		link, ok := timeNode.Parent().Attr("href")
-		if !ok {
-			return
-		}
-		if link == "" {
-			log.Println("empty link")
-			return
-		}
-		parts := strings.Split(link, "/")
-		id := parts[len(parts)-1]
-		if _, ok := ids[id]; ok {
-			return
-		}
-
-		p.ID = id
-
-		// Search user ID
-		p.UserID = strings.TrimPrefix(strings.TrimSpace(s.Find(`div[data-testid="User-Name"] a > div > span`).First().Text()), "@")
-
-		// Search user name
-		p.UserName = strings.TrimSpace(s.Find(`div[data-testid="User-Name"] a`).First().Text())
-
-		// Search post text
-		text := strings.TrimSpace(s.Find(`div[data-testid="tweetText"]`).First().Text())
-		p.Text = strings.ReplaceAll(text, "\n", " ")
-
-		// Search stats (comments, retweets, likes, views)
-		s.Find(`span[data-testid="app-text-transition-container"]`).Each(func(i int, s *goquery.Selection) {
-			v := s.Text()
-			n, err := toNumber(v)
-			if err != nil {
-				log.Printf("error parsing number: %v\n", err)
-				return
-			}
-			switch i {
-			case 0:
-				p.Comments = n
-			case 1:
-				p.Retweets = n
-			case 2:
-				p.Likes = n
-			case 3:
-				p.Views = n
-			}
-		})
-		if p.ID == "" || p.UserID != "" || p.Text != "" {
-			posts = append(posts, &p)
-		}
-	})
-	return posts, nil
-}
-
-func toNumber(s string) (int, error) {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return 0, nil
-	}
-	s = strings.ReplaceAll(s, ",", "")
-	mul := 1
-	switch {
-	case strings.HasSuffix(s, "K"):
-		mul = 1000
-	case strings.HasSuffix(s, "M"):
-		mul = 1000000
-	case strings.HasSuffix(s, "B"):
-		mul = 1000000000
-	}
-	var views int
-	if v := decimalRegex.FindString(s); v != "" {
-		f, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return 0, fmt.Errorf("error parsing views %s: %w", s, err)
-		}
-		views = int(f * float64(mul))
-	} else if v := numberRegex.FindString(s); v != "" {
-		n, err := strconv.Atoi(v)
-		if err != nil {
-			return 0, fmt.Errorf("error parsing views %s: %w", s, err)
+	for _, p := range ParseHTML(doc) {
+		if _, ok := ids[p.ID]; ok {
+			continue
 		}
-		views = n * mul
-	} else {
-		return 0, fmt.Errorf("error parsing views %s", s)
+		posts = append(posts, p)
 	}
-	return views, nil
+	return posts, nil
 }
 
-var numberRegex = regexp.MustCompile(`\d+`)
-var decimalRegex = regexp.MustCompile(`\d+.\d+`)
-
 func getHTML(ctx context.Context, sel string) (*goquery.Document, error) {
 	// Obtain the document
 	var html string
@@ -310,29 +309,3 @@ func scrollDown(ctx context.Context) error {
 		}),
 	)
 }
-
-func mouseMove(ctx context.Context, sel string) error {
-	// Variables to store the x and y coordinates
-	var x, y float64
-
-	// First, get the x and y coordinates of the center of the div
-	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(`
-	var rect = document.querySelector('%s').getBoundingClientRect();
-	[rect.left + (rect.width / 2), rect.top + (rect.height / 2)]
-`, sel), &[]*float64{&x, &y})); err != nil {
-		return fmt.Errorf("twitter: couldn't get coordinates: %w", err)
-	}
-
-	// Scroll to that position
-	if y < 100 {
-		if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(`window.scrollTo(%f, %f)`, x, y-100), nil)); err != nil {
-			return fmt.Errorf("twitter: couldn't scroll to coordinates: %w", err)
-		}
-	}
-
-	// Now, move the mouse to the retrieved coordinates
-	if err := chromedp.Run(ctx, chromedp.MouseEvent(input.MouseMoved, x, y)); err != nil {
-		return fmt.Errorf("twitter: couldn't move mouse: %w", err)
-	}
-	return nil
-}
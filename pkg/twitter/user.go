@@ -0,0 +1,117 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	Location  string    `json:"location"`
+	Website   string    `json:"website"`
+	Joined    time.Time `json:"joined"`
+	Followers int       `json:"followers"`
+	Following int       `json:"following"`
+	Verified  bool      `json:"verified"`
+	PinnedID  string    `json:"pinned_id"`
+}
+
+// profileOperations are the GraphQL operations that return a profile
+var profileOperations = map[string]struct{}{
+	"UserByScreenName": {},
+}
+
+// Profile obtains the profile of the given user.
+// Profiles are cached, so each user is only visited once per browser.
+func (c *Browser) Profile(parent context.Context, handle string) (*User, error) {
+	handle, err := parseUsername(handle)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.ToLower(handle)
+
+	c.profilesLck.Lock()
+	u, ok := c.profiles[key]
+	c.profilesLck.Unlock()
+	if ok {
+		return u, nil
+	}
+
+	u, err = c.visitProfile(parent, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	c.profilesLck.Lock()
+	defer c.profilesLck.Unlock()
+	c.profiles[key] = u
+	return u, nil
+}
+
+func (c *Browser) visitProfile(parent context.Context, handle string) (*User, error) {
+	// Create a new tab based on client context
+	ctx, cancel := chromedp.NewContext(c.browserContext)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-parent.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Capture the profile from GraphQL responses
+	var lck sync.Mutex
+	var captured *User
+	listen(ctx, profileOperations, func(op string, body []byte) {
+		u, err := ParseUser(body)
+		if err != nil {
+			log.Printf("twitter: couldn't parse %s response: %v\n", op, err)
+			return
+		}
+		lck.Lock()
+		defer lck.Unlock()
+		captured = u
+	})
+
+	// Navigate to the profile
+	unlock := c.rateLimit.Lock(parent)
+	defer unlock()
+	if err := chromedp.Run(ctx,
+		chromedp.Navigate(fmt.Sprintf("https://x.com/%s", handle)),
+		chromedp.WaitVisible(`div[data-testid="UserName"]`, chromedp.ByQuery),
+	); err != nil {
+		return nil, fmt.Errorf("twitter: couldn't navigate to profile %s: %w", handle, err)
+	}
+
+	// Wait for the GraphQL response
+	for i := 0; i < 20; i++ {
+		lck.Lock()
+		u := captured
+		lck.Unlock()
+		if u != nil {
+			return u, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// Fallback to DOM parsing
+	doc, err := getHTML(ctx, "main")
+	if err != nil {
+		return nil, err
+	}
+	return ParseProfile(doc, handle)
+}
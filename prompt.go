@@ -0,0 +1,56 @@
+package twai
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// prompt is a prompt rendered as a text/template.
+type prompt struct {
+	raw  string
+	tmpl *template.Template
+}
+
+// newPrompt creates a prompt from the prompt file, if provided, or from the
+// prompt text.
+// If the prompt doesn't contain any template action, the suffix template is
+// appended, so plain prompts keep working.
+// The prompt is rendered with the zero value of the data, so invalid fields
+// are reported before asking the model.
+func newPrompt(text, file, suffix string, zero any) (*prompt, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read prompt file: %w", err)
+		}
+		text = string(b)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("prompt is empty")
+	}
+	if !strings.Contains(text, "{{") {
+		text += suffix
+	}
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse prompt template: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, zero); err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return &prompt{raw: text, tmpl: tmpl}, nil
+}
+
+// render executes the prompt template with the given data.
+func (p *prompt) render(data any) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("couldn't render prompt: %w", err)
+	}
+	return buf.String(), nil
+}
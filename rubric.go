@@ -0,0 +1,158 @@
+package twai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// rubric defines the criteria used to score tweets.
+type rubric struct {
+	// Prompt is the prompt template, the tweet text is appended if no fields
+	// are used
+	Prompt   string          `yaml:"prompt"`
+	Scale    rubricScale     `yaml:"scale"`
+	Criteria []*criterion    `yaml:"criteria"`
+	Examples []*rubricSample `yaml:"examples"`
+}
+
+type rubricScale struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+// defaultScale is the scale of the default prompts.
+var defaultScale = rubricScale{Min: 1, Max: 10}
+
+type criterion struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Weight      float64 `yaml:"weight"`
+}
+
+// rubricSample is a few-shot example of a scored tweet.
+type rubricSample struct {
+	Text      string             `yaml:"text"`
+	Scores    map[string]float64 `yaml:"scores"`
+	Rationale string             `yaml:"rationale"`
+}
+
+const defaultRubricPrompt = "Rate the following tweet using the criteria below."
+
+// loadRubric reads and validates a YAML rubric file.
+func loadRubric(path string) (*rubric, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read rubric: %w", err)
+	}
+	var r rubric
+	if err := yaml.UnmarshalStrict(b, &r); err != nil {
+		return nil, fmt.Errorf("couldn't parse rubric: %w", err)
+	}
+	if r.Prompt == "" {
+		r.Prompt = defaultRubricPrompt
+	}
+	if r.Scale.Min == 0 && r.Scale.Max == 0 {
+		r.Scale = defaultScale
+	}
+	if r.Scale.Max <= r.Scale.Min {
+		return nil, fmt.Errorf("invalid rubric scale %v-%v", r.Scale.Min, r.Scale.Max)
+	}
+	if len(r.Criteria) == 0 {
+		return nil, fmt.Errorf("rubric needs at least 1 criterion")
+	}
+	names := map[string]struct{}{}
+	for _, c := range r.Criteria {
+		if c.Name == "" {
+			return nil, fmt.Errorf("rubric criterion without name")
+		}
+		if _, ok := names[c.Name]; ok {
+			return nil, fmt.Errorf("duplicated rubric criterion %q", c.Name)
+		}
+		// Criteria are written as columns along with the tweet columns
+		if isTweetColumn(c.Name) || strings.HasPrefix(c.Name, modelColumn) {
+			return nil, fmt.Errorf("rubric criterion %q is the name of a tweet column", c.Name)
+		}
+		names[c.Name] = struct{}{}
+		if c.Weight < 0 {
+			return nil, fmt.Errorf("negative weight in rubric criterion %q", c.Name)
+		}
+		if c.Weight == 0 {
+			c.Weight = 1
+		}
+	}
+	for i, e := range r.Examples {
+		for name := range e.Scores {
+			if _, ok := names[name]; !ok {
+				return nil, fmt.Errorf("unknown criterion %q in rubric example %d", name, i+1)
+			}
+		}
+	}
+	return &r, nil
+}
+
+// template returns the prompt template with the criteria and the examples.
+// The tweet text is appended if the prompt doesn't use any field.
+func (r *rubric) template() string {
+	var sb strings.Builder
+	sb.WriteString("\n\nCriteria:\n")
+	for _, c := range r.Criteria {
+		fmt.Fprintf(&sb, "- %s", c.Name)
+		if c.Description != "" {
+			fmt.Fprintf(&sb, ": %s", c.Description)
+		}
+		sb.WriteString("\n")
+	}
+	for i, e := range r.Examples {
+		answer, _ := json.Marshal(&structuredAnswer{
+			Criteria:  e.Scores,
+			Rationale: e.Rationale,
+		})
+		fmt.Fprintf(&sb, "\nExample %d:\n%s\nAnswer: %s\n", i+1, e.Text, answer)
+	}
+
+	// Escape template actions in the rubric texts
+	text := r.Prompt + strings.ReplaceAll(sb.String(), "{{", `{{"{{"}}`)
+	if !strings.Contains(r.Prompt, "{{") {
+		text += "\nTweet:\n{{.Text}}"
+	}
+	return text
+}
+
+// weightsKey is added to the prompt key, so totals stored with different
+// criteria weights aren't reused.
+func (r *rubric) weightsKey() string {
+	var weights []string
+	for _, c := range r.Criteria {
+		weights = append(weights, fmt.Sprintf("%s=%v", c.Name, c.Weight))
+	}
+	return "\n\nweights: " + strings.Join(weights, ",")
+}
+
+// instructions returns the answer format appended to the prompt.
+func (r *rubric) instructions() string {
+	return fmt.Sprintf("\n\nAnswer only with a JSON object with these fields:\n"+
+		"- \"criteria\": an object with the score from %v to %v of each criterion\n"+
+		"- \"rationale\": a short explanation of the scores", r.Scale.Min, r.Scale.Max)
+}
+
+// total returns the weighted average of the scores of the criteria.
+// Criteria missing in the scores are ignored.
+func (r *rubric) total(scores map[string]float64) (float64, error) {
+	var sum, weights float64
+	for _, c := range r.Criteria {
+		v, ok := scores[c.Name]
+		if !ok {
+			continue
+		}
+		sum += c.Weight * v
+		weights += c.Weight
+	}
+	if weights == 0 {
+		return 0, fmt.Errorf("no rubric criteria found in answer")
+	}
+	return round(sum / weights), nil
+}
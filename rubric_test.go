@@ -0,0 +1,67 @@
+package twai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRubricKey(t *testing.T) {
+	base := `
+criteria:
+  - name: relevance
+    weight: 2
+  - name: clarity
+`
+	tests := []struct {
+		name    string
+		rubric  string
+		sameKey bool
+	}{
+		{name: "same rubric", rubric: base, sameKey: true},
+		{
+			name: "default weight",
+			rubric: `
+criteria:
+  - name: relevance
+    weight: 2
+  - name: clarity
+    weight: 1
+`,
+			sameKey: true,
+		},
+		{
+			name: "different weight",
+			rubric: `
+criteria:
+  - name: relevance
+    weight: 3
+  - name: clarity
+`,
+			sameKey: false,
+		},
+	}
+	want := scorerKey(t, base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scorerKey(t, tt.rubric)
+			if (got == want) != tt.sameKey {
+				t.Errorf("got same key %v, want %v", got == want, tt.sameKey)
+			}
+		})
+	}
+}
+
+// scorerKey returns the prompt key of a scorer using the rubric.
+func scorerKey(t *testing.T, rubric string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rubric.yaml")
+	if err := os.WriteFile(path, []byte(rubric), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newScorer(&ScoreConfig{Rubric: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.key
+}
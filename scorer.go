@@ -0,0 +1,386 @@
+package twai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/igolaizola/twai/pkg/openai"
+	"github.com/igolaizola/twai/pkg/twitter"
+)
+
+// DefaultScorePrompt is the default prompt of the score command
+const DefaultScorePrompt = "Rate the following tweet from 1 to 10 based on relevance, clarity, engagement, and impact. Only answer with a number."
+
+// scoreCriteria are the criteria requested in structured mode
+var scoreCriteria = []string{"relevance", "clarity", "engagement", "impact"}
+
+// structuredPrompt is appended to score prompts in structured mode
+const structuredPrompt = `
+
+Answer only with a JSON object with these fields:
+- "criteria": an object with the score from 1 to 10 of each criterion (%s)
+- "score": the overall score from 1 to 10
+- "rationale": a short explanation of the score`
+
+// structuredAnswer is the answer of the model in structured mode.
+type structuredAnswer struct {
+	Criteria  map[string]float64 `json:"criteria"`
+	Score     float64            `json:"score,omitempty"`
+	Rationale string             `json:"rationale"`
+	// Confidence is obtained from log probabilities
+	Confidence float64 `json:"-"`
+}
+
+// scorer asks the model, or an ensemble of models, to score tweets.
+type scorer struct {
+	models       []*scoreModel
+	ensemble     string
+	logprobs     bool
+	prompt       *prompt
+	rubric       *rubric
+	structured   bool
+	instructions string
+	shots        []openai.Message
+	// key identifies the prompt, the instructions and the examples
+	key string
+	// model identifies the models and the ensemble method
+	model string
+}
+
+func newScorer(cfg *ScoreConfig, cache *openai.Cache) (*scorer, error) {
+	s := &scorer{
+		structured: cfg.Structured,
+		logprobs:   cfg.LogProbs,
+		model:      cfg.Model,
+	}
+
+	// Use an ensemble of models if several models are provided
+	if cfg.Models != "" {
+		models, err := newScoreModels(cfg, cache)
+		if err != nil {
+			return nil, err
+		}
+		s.models = models
+	} else {
+		s.models = []*scoreModel{{
+			name: cfg.Model,
+			client: openai.New(&openai.Config{
+				Debug: cfg.Debug,
+				Model: cfg.Model,
+				Host:  cfg.Host,
+				Token: cfg.Token,
+				Cache: cache,
+			}),
+		}}
+	}
+	if len(s.models) > 1 {
+		s.ensemble = cfg.Ensemble
+		switch s.ensemble {
+		case "":
+			s.ensemble = ensembleMean
+		case ensembleMean, ensembleMedian, ensembleVote:
+		default:
+			return nil, fmt.Errorf("invalid ensemble method %q", s.ensemble)
+		}
+		var names []string
+		for _, m := range s.models {
+			names = append(names, m.name)
+		}
+		s.model = fmt.Sprintf("%s(%s)", s.ensemble, strings.Join(names, ","))
+	}
+
+	// Load the rubric, its prompt replaces the configured prompt
+	promptText, promptFile := cfg.Prompt, cfg.PromptFile
+	if cfg.Rubric != "" {
+		rub, err := loadRubric(cfg.Rubric)
+		if err != nil {
+			return nil, err
+		}
+		promptText, promptFile = rub.template(), ""
+		s.rubric = rub
+		s.structured = true
+	}
+
+	if s.logprobs && s.structured {
+		return nil, fmt.Errorf("log probabilities can't be used with structured answers")
+	}
+
+	// Prompt template, the tweet text is appended to plain prompts
+	prompt, err := newPrompt(promptText, promptFile, "\n\n{{.Text}}", &twitter.Post{})
+	if err != nil {
+		return nil, err
+	}
+	s.prompt = prompt
+
+	// Instructions to answer with a JSON object in structured mode
+	switch {
+	case s.rubric != nil:
+		s.instructions = s.rubric.instructions()
+	case s.structured:
+		s.instructions = fmt.Sprintf(structuredPrompt, strings.Join(scoreCriteria, ", "))
+	}
+
+	// Few-shot examples labelled by humans
+	if cfg.Examples != "" {
+		ls, err := readLabelled(cfg.Examples)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range ls {
+			if err := s.addShot(&l.Post, l.Score); err != nil {
+				return nil, err
+			}
+		}
+		log.Printf("using %d examples from %s\n", len(ls), cfg.Examples)
+	}
+	s.key = prompt.raw + s.instructions + shotsKey(s.shots)
+	if s.rubric != nil {
+		s.key += s.rubric.weightsKey()
+	}
+	if s.logprobs {
+		s.key += logprobsKey
+	}
+	return s, nil
+}
+
+// addShot adds a few-shot example with the human score of the post.
+func (s *scorer) addShot(post *twitter.Post, score float64) error {
+	question, err := s.prompt.render(post)
+	if err != nil {
+		return err
+	}
+	answer := strconv.FormatFloat(score, 'f', -1, 64)
+	if s.structured {
+		// Humans only provide the overall score, so it is used for every
+		// criterion
+		a := &structuredAnswer{Criteria: map[string]float64{}}
+		for _, name := range s.criteria() {
+			a.Criteria[name] = score
+		}
+		if s.rubric == nil {
+			a.Score = score
+		}
+		b, err := json.Marshal(a)
+		if err != nil {
+			return fmt.Errorf("couldn't marshal example answer: %w", err)
+		}
+		answer = string(b)
+	}
+	s.shots = append(s.shots, shot(question+s.instructions, answer)...)
+	return nil
+}
+
+// criteria returns the names of the criteria requested in structured mode.
+func (s *scorer) criteria() []string {
+	if s.rubric == nil {
+		return scoreCriteria
+	}
+	var names []string
+	for _, c := range s.rubric.Criteria {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// scale returns the range of valid scores.
+func (s *scorer) scale() rubricScale {
+	if s.rubric == nil {
+		return defaultScale
+	}
+	return s.rubric.Scale
+}
+
+// score asks the models for the score of the post and combines their
+// answers.
+func (s *scorer) score(ctx context.Context, post *twitter.Post) (*Tweet, error) {
+	msg, err := s.prompt.render(post)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.models) == 1 {
+		answer, err := s.ask(ctx, s.models[0], msg)
+		if err != nil {
+			return nil, err
+		}
+		tw := newTweet(post, answer.Score)
+		tw.Confidence = answer.Confidence
+		tw.Criteria = answer.Criteria
+		tw.Rationale = answer.Rationale
+		return tw, nil
+	}
+
+	// Ask all the models at the same time
+	answers := make([]*structuredAnswer, len(s.models))
+	errs := make([]error, len(s.models))
+	var wg sync.WaitGroup
+	for i, m := range s.models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers[i], errs[i] = s.ask(ctx, m, msg)
+		}()
+	}
+	wg.Wait()
+
+	// Combine the answers of the models that didn't fail
+	models := Scores{}
+	criteria := map[string][]float64{}
+	var scores, confidences []float64
+	var rationales []string
+	for i, m := range s.models {
+		if errs[i] != nil {
+			log.Printf("%s: %v\n", m.name, errs[i])
+			continue
+		}
+		a := answers[i]
+		models[m.name] = a.Score
+		scores = append(scores, a.Score)
+		confidences = append(confidences, a.Confidence)
+		for name, v := range a.Criteria {
+			criteria[name] = append(criteria[name], v)
+		}
+		if a.Rationale != "" {
+			rationales = append(rationales, fmt.Sprintf("%s: %s", m.name, a.Rationale))
+		}
+	}
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("all models failed: %w", errors.Join(errs...))
+	}
+	tw := newTweet(post, round(combine(s.ensemble, scores)))
+	tw.Models = models
+	tw.Disagreement = round(stddev(scores))
+	tw.Confidence = round(mean(confidences))
+	if len(criteria) > 0 {
+		tw.Criteria = Scores{}
+		for name, vs := range criteria {
+			tw.Criteria[name] = round(combine(s.ensemble, vs))
+		}
+	}
+	tw.Rationale = strings.Join(rationales, "\n")
+	return tw, nil
+}
+
+// ask asks a model for the score of the rendered prompt.
+func (s *scorer) ask(ctx context.Context, m *scoreModel, msg string) (*structuredAnswer, error) {
+	// Answers are parsed before they are cached, so answers that can't be
+	// parsed are asked again
+	var answer *structuredAnswer
+	if s.logprobs {
+		// Use the expected score of the probabilities of the answers
+		if _, _, err := m.client.ChatProbs(ctx, withShots(s.shots, msg), func(resp string, probs map[string]float64) error {
+			if score, confidence, ok := probsScore(probs, s.scale()); ok {
+				answer = &structuredAnswer{Score: round(score), Confidence: round(confidence)}
+				return nil
+			}
+			log.Println("no score probabilities in response, using the answer")
+			score, err := parseScore(resp)
+			if err != nil {
+				return err
+			}
+			answer = &structuredAnswer{Score: score}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return answer, nil
+	}
+	if !s.structured {
+		if _, err := m.client.Chat(ctx, withShots(s.shots, msg), func(resp string) error {
+			score, err := parseScore(resp)
+			if err != nil {
+				return err
+			}
+			answer = &structuredAnswer{Score: score}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return answer, nil
+	}
+
+	var raw rawAnswer
+	if err := m.client.JSONChat(ctx, withShots(s.shots, msg+s.instructions), &raw, func() error {
+		var err error
+		answer, err = s.parseAnswer(&raw)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// rawAnswer is the JSON answer of the model in structured mode.
+type rawAnswer struct {
+	structuredAnswer
+	// Score is a pointer to know if the model left it out
+	Score *float64 `json:"score"`
+}
+
+// parseAnswer returns the structured answer with the requested criteria and
+// the score.
+func (s *scorer) parseAnswer(raw *rawAnswer) (*structuredAnswer, error) {
+	answer := raw.structuredAnswer
+
+	// Only keep the requested criteria, they are written as columns
+	criteria := map[string]float64{}
+	for _, name := range s.criteria() {
+		if v, ok := answer.Criteria[name]; ok {
+			criteria[name] = v
+		}
+	}
+	answer.Criteria = criteria
+
+	switch {
+	case s.rubric != nil:
+		// Use the weighted total of the rubric criteria
+		total, err := s.rubric.total(answer.Criteria)
+		if err != nil {
+			return nil, err
+		}
+		answer.Score = total
+	case raw.Score != nil:
+		answer.Score = *raw.Score
+	case len(criteria) > 0:
+		// Use the mean of the criteria if the model left out the score
+		var vs []float64
+		for _, v := range criteria {
+			vs = append(vs, v)
+		}
+		answer.Score = round(mean(vs))
+	default:
+		return nil, errors.New("no score found in response")
+	}
+	return &answer, nil
+}
+
+var (
+	scoreRegex = regexp.MustCompile(`\d+(\.\d+)?`)
+	// scaleRegex matches mentions of the scale, like "1-10", "1 to 10",
+	// "/10" or "out of 10"
+	scaleRegex = regexp.MustCompile(`(?i)\d+\s*(-|–|to)\s*\d+|(/|\bout of)\s*\d+`)
+)
+
+// parseScore returns the score of the answer.
+// Mentions of the scale are ignored and the first remaining number is used
+// (e.g. "On a scale of 1-10: 8" is 8).
+// Answers without a number return an error, so they aren't stored and are
+// asked again in the next run.
+func parseScore(resp string) (float64, error) {
+	resp = scaleRegex.ReplaceAllString(resp, "")
+	match := scoreRegex.FindString(resp)
+	if match == "" {
+		return 0, errors.New("no number found in response")
+	}
+	n, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't parse number from response: %w", err)
+	}
+	return n, nil
+}
@@ -2,37 +2,96 @@ package twai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math"
-	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/gocarina/gocsv"
 	"github.com/igolaizola/twai/pkg/openai"
+	"github.com/igolaizola/twai/pkg/rank"
+	"github.com/igolaizola/twai/pkg/store"
 	"github.com/igolaizola/twai/pkg/twitter"
 )
 
 type ScrapeConfig struct {
-	Debug       bool
-	CookieFile  string
-	ShowBrowser bool
-	Page        string
-	N           int
-	Followers   bool
-	Output      string
+	Debug        bool
+	CookieFile   string
+	ShowBrowser  bool
+	Page         string
+	Search       string
+	SearchTab    string
+	N            int
+	Followers    bool
+	SkipRetweets bool
+	SinceID      string
+	Since        string
+	Until        string
+	Watermark    string
+	Output       string
+	Format       string
+	DB           string
 }
 
 func Scrape(ctx context.Context, cfg *ScrapeConfig) error {
 	log.Println("running")
 	defer log.Println("finished")
 
+	postsConfig := &twitter.PostsConfig{
+		N:         cfg.N,
+		Followers: cfg.Followers,
+		SinceID:   cfg.SinceID,
+	}
+	var err error
+	if postsConfig.Since, err = parseTime(cfg.Since); err != nil {
+		return fmt.Errorf("invalid since: %w", err)
+	}
+	if postsConfig.Until, err = parseTime(cfg.Until); err != nil {
+		return fmt.Errorf("invalid until: %w", err)
+	}
+
+	// Create the output, tweets are streamed if the format supports it
+	out, err := newOutput[*twitter.Post](cfg.Output, cfg.Format, cfg.DB != "")
+	if err != nil {
+		return err
+	}
+	postsConfig.OnPost = func(p *twitter.Post) {
+		if cfg.SkipRetweets && p.RetweetedBy != "" {
+			return
+		}
+		if err := out.stream(p); err != nil {
+			log.Println(err)
+		}
+	}
+	var complete bool
+	postsConfig.OnComplete = func() {
+		complete = true
+	}
+
+	// Use the watermark of the previous run if no since id is provided
+	var watermark string
+	if cfg.Watermark != "" {
+		b, err := os.ReadFile(cfg.Watermark)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("couldn't read watermark: %w", err)
+		default:
+			watermark = strings.TrimSpace(string(b))
+		}
+		if postsConfig.SinceID == "" {
+			postsConfig.SinceID = watermark
+		}
+	}
+
 	b := twitter.NewBrowser(&twitter.BrowserConfig{
 		Wait:        1 * time.Second,
 		CookieStore: twitter.NewCookieStore(cfg.CookieFile),
@@ -44,35 +103,258 @@ func Scrape(ctx context.Context, cfg *ScrapeConfig) error {
 	defer func() { _ = b.Stop() }()
 
 	// Get tweets
-	posts, err := b.Posts(ctx, cfg.Page, cfg.N, cfg.Followers)
+	var posts []*twitter.Post
+	if cfg.Search != "" {
+		posts, err = b.Search(ctx, cfg.Search, cfg.SearchTab, postsConfig)
+	} else {
+		posts, err = b.Posts(ctx, cfg.Page, postsConfig)
+	}
 	if err != nil {
 		return err
 	}
 
+	// Remove retweets of other accounts
+	if cfg.SkipRetweets {
+		var filtered []*twitter.Post
+		for _, p := range posts {
+			if p.RetweetedBy == "" {
+				filtered = append(filtered, p)
+			}
+		}
+		posts = filtered
+	}
+
 	// Order tweets by score and views
 	sort.Slice(posts, func(i, j int) bool {
 		return posts[i].Views > posts[j].Views
 	})
 
-	// Marshal tweets to CSV
-	data, err := gocsv.MarshalBytes(&posts)
+	// Store tweets in the database if provided
+	if cfg.DB != "" {
+		if err := storePosts(ctx, cfg.DB, posts); err != nil {
+			return err
+		}
+		log.Printf("stored %d tweets in %s\n", len(posts), cfg.DB)
+	}
+
+	// Write tweets to the output
+	if err := out.close(posts); err != nil {
+		return err
+	}
+
+	// Update the watermark with the newest tweet
+	if cfg.Watermark != "" {
+		if watermark != "" && !complete {
+			log.Println("previous watermark not reached, keeping it")
+		}
+		next := nextWatermark(watermark, posts, complete)
+		if next != "" && next != watermark {
+			if err := os.WriteFile(cfg.Watermark, []byte(next), 0644); err != nil {
+				return fmt.Errorf("couldn't write watermark: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// nextWatermark returns the id of the newest post if all the posts newer than
+// the watermark were obtained.
+// Otherwise the watermark is kept, so the posts between the oldest post
+// obtained and the watermark are obtained in the next run.
+func nextWatermark(watermark string, posts []*twitter.Post, complete bool) string {
+	if watermark != "" && !complete {
+		return watermark
+	}
+	for _, p := range posts {
+		if watermark == "" || twitter.CompareIDs(p.ID, watermark) > 0 {
+			watermark = p.ID
+		}
+	}
+	return watermark
+}
+
+type ThreadConfig struct {
+	Debug       bool
+	CookieFile  string
+	ShowBrowser bool
+	URL         string
+	N           int
+	Output      string
+	Format      string
+}
+
+func Thread(ctx context.Context, cfg *ThreadConfig) error {
+	log.Println("running")
+	defer log.Println("finished")
+
+	b := twitter.NewBrowser(&twitter.BrowserConfig{
+		Wait:        1 * time.Second,
+		CookieStore: twitter.NewCookieStore(cfg.CookieFile),
+		Headless:    !cfg.ShowBrowser,
+	})
+	if err := b.Start(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = b.Stop() }()
+
+	out, err := newOutput[*twitter.Post](cfg.Output, cfg.Format, false)
+	if err != nil {
+		return err
+	}
+
+	// Get the thread and its replies
+	posts, err := b.Conversation(ctx, cfg.URL, cfg.N)
+	if err != nil {
+		return err
+	}
+
+	// Order tweets by depth, keeping the conversation order
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Depth < posts[j].Depth
+	})
+
+	// Write tweets to the output
+	if err := out.close(posts); err != nil {
+		return err
+	}
+	return nil
+}
+
+type UsersConfig struct {
+	Debug       bool
+	CookieFile  string
+	ShowBrowser bool
+	Handles     string
+	Input       string
+	Output      string
+	Format      string
+}
+
+func Users(ctx context.Context, cfg *UsersConfig) error {
+	log.Println("running")
+	defer log.Println("finished")
+
+	// Obtain handles from config and from the authors of the input tweets
+	var handles []string
+	seen := map[string]struct{}{}
+	add := func(h string) {
+		h = strings.TrimPrefix(strings.TrimSpace(h), "@")
+		if h == "" {
+			return
+		}
+		if _, ok := seen[strings.ToLower(h)]; ok {
+			return
+		}
+		seen[strings.ToLower(h)] = struct{}{}
+		handles = append(handles, h)
+	}
+	for _, h := range strings.Split(cfg.Handles, ",") {
+		add(h)
+	}
+	if cfg.Input != "" {
+		posts, err := readItems[*twitter.Post](cfg.Input)
+		if err != nil {
+			return err
+		}
+		for _, p := range posts {
+			add(p.UserID)
+		}
+	}
+	if len(handles) == 0 {
+		return fmt.Errorf("need at least 1 user handle")
+	}
+
+	b := twitter.NewBrowser(&twitter.BrowserConfig{
+		Wait:        1 * time.Second,
+		CookieStore: twitter.NewCookieStore(cfg.CookieFile),
+		Headless:    !cfg.ShowBrowser,
+	})
+	if err := b.Start(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = b.Stop() }()
+
+	out, err := newOutput[*twitter.User](cfg.Output, cfg.Format, false)
 	if err != nil {
-		return fmt.Errorf("couldn't marshal tweets to csv: %w", err)
+		return err
 	}
-	// Write to file if output is provided
-	if cfg.Output != "" {
-		if err := os.WriteFile(cfg.Output, data, 0644); err != nil {
-			return fmt.Errorf("couldn't write tweets to file: %w", err)
+
+	// Get profiles
+	var users []*twitter.User
+	for i, h := range handles {
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("user %d/%d: %s\n", i+1, len(handles), h)
+		u, err := b.Profile(ctx, h)
+		if err != nil {
+			log.Println(err)
+			continue
 		}
-		fmt.Println("created file:", cfg.Output)
+		users = append(users, u)
+		if err := out.stream(u); err != nil {
+			return err
+		}
+	}
+
+	// Write users to the output
+	if err := out.close(users); err != nil {
+		return err
+	}
+	return nil
+}
+
+type FollowersConfig struct {
+	Debug       bool
+	CookieFile  string
+	ShowBrowser bool
+	Handle      string
+	Following   bool
+	N           int
+	Output      string
+	Format      string
+}
+
+func Followers(ctx context.Context, cfg *FollowersConfig) error {
+	log.Println("running")
+	defer log.Println("finished")
+
+	b := twitter.NewBrowser(&twitter.BrowserConfig{
+		Wait:        1 * time.Second,
+		CookieStore: twitter.NewCookieStore(cfg.CookieFile),
+		Headless:    !cfg.ShowBrowser,
+	})
+	if err := b.Start(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = b.Stop() }()
+
+	out, err := newOutput[*twitter.User](cfg.Output, cfg.Format, false)
+	if err != nil {
+		return err
+	}
+
+	// Get followers or following users
+	var users []*twitter.User
+	if cfg.Following {
+		users, err = b.Following(ctx, cfg.Handle, cfg.N)
 	} else {
-		fmt.Println(string(data))
+		users, err = b.Followers(ctx, cfg.Handle, cfg.N)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Write users to the output
+	if err := out.close(users); err != nil {
+		return err
 	}
 	return nil
 }
 
 type Tweet struct {
-	Score int `json:"score" csv:"score"`
+	Score       float64 `json:"score" csv:"score"`
+	Uncertainty float64 `json:"uncertainty,omitempty" csv:"uncertainty"`
 
 	Comments int `json:"comments" csv:"comments"`
 	Retweets int `json:"retweets" csv:"retweets"`
@@ -82,41 +364,161 @@ type Tweet struct {
 	Time time.Time `json:"time" csv:"time"`
 	Text string    `json:"text" csv:"text"`
 	Link string    `json:"link" csv:"link"`
+
+	Confidence float64 `json:"confidence,omitempty" csv:"confidence"`
+	Criteria   Scores  `json:"criteria,omitempty" csv:"-"`
+	Rationale  string  `json:"rationale,omitempty" csv:"rationale"`
+
+	Models       Scores  `json:"models,omitempty" csv:"-"`
+	Disagreement float64 `json:"disagreement,omitempty" csv:"disagreement"`
+
+	post *twitter.Post
+}
+
+// Scores are scores by name, like the score of each criterion or model, stored
+// as a JSON object in CSV files.
+type Scores map[string]float64
+
+// MarshalCSV implements gocsv.TypeMarshaller.
+func (c Scores) MarshalCSV() (string, error) {
+	if len(c) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(map[string]float64(c))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// UnmarshalCSV implements gocsv.TypeUnmarshaller.
+func (c *Scores) UnmarshalCSV(s string) error {
+	if s == "" {
+		*c = nil
+		return nil
+	}
+	var v map[string]float64
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// names returns the sorted names of the scores.
+func (c Scores) names() []string {
+	var names []string
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modelColumn is the prefix of the CSV columns with the score of each model.
+const modelColumn = "score_"
+
+// columns returns the score of each criterion and each model as extra CSV
+// columns.
+func (t *Tweet) columns() []column {
+	var cols []column
+	for _, name := range t.Criteria.names() {
+		cols = append(cols, column{name: name, value: formatScore(t.Criteria[name])})
+	}
+	for _, name := range t.Models.names() {
+		cols = append(cols, column{name: modelColumn + name, value: formatScore(t.Models[name])})
+	}
+	return cols
+}
+
+// isTweetColumn returns whether the name is a fixed CSV column of tweets.
+func isTweetColumn(name string) bool {
+	typ := reflect.TypeOf(Tweet{})
+	for i := 0; i < typ.NumField(); i++ {
+		tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("csv"), ",")
+		if tag != "" && tag != "-" && strings.EqualFold(tag, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTweet(post *twitter.Post, score float64) *Tweet {
+	return &Tweet{
+		Score: score,
+
+		Comments: post.Comments,
+		Retweets: post.Retweets,
+		Likes:    post.Likes,
+		Views:    post.Views,
+
+		Time: post.Time,
+		Text: post.Text,
+		Link: postLink(post),
+
+		post: post,
+	}
+}
+
+// postLink returns the link of the post, or the link of the profile if the
+// post was created from a user.
+func postLink(post *twitter.Post) string {
+	if post.ID == post.UserID {
+		return fmt.Sprintf("https://x.com/%s", post.UserID)
+	}
+	return fmt.Sprintf("https://x.com/%s/status/%s", post.UserID, post.ID)
 }
 
 type ScoreConfig struct {
 	Debug       bool
 	Concurrency int
 	Input       string
+	Users       bool
 	Output      string
+	Format      string
 	Prompt      string
+	PromptFile  string
 	Model       string
 	Host        string
 	Token       string
+	DB          string
+	Structured  bool
+	Rubric      string
+	Examples    string
+	LogProbs    bool
+	CacheDir    string
+	NoCache     bool
+
+	// Ensemble of models, hosts and tokens as comma separated lists
+	Models       string
+	Hosts        string
+	Tokens       string
+	Ensemble     string
+	Disagreement float64
 }
 
 func Score(ctx context.Context, cfg *ScoreConfig) error {
 	log.Println("running")
 	defer log.Println("finished")
 
-	var posts []*twitter.Post
-	b, err := os.ReadFile(cfg.Input)
+	posts, err := readPosts(ctx, cfg.Input, cfg.DB, cfg.Users)
 	if err != nil {
-		return fmt.Errorf("couldn't read tweets from file: %w", err)
-	}
-	if err := gocsv.UnmarshalBytes(b, &posts); err != nil {
-		return fmt.Errorf("couldn't unmarshal tweets from csv: %w", err)
+		return err
 	}
 	if len(posts) < 1 {
 		return fmt.Errorf("need at least 1 tweet to score")
 	}
 
-	c := openai.New(&openai.Config{
-		Debug: cfg.Debug,
-		Model: cfg.Model,
-		Host:  cfg.Host,
-		Token: cfg.Token,
-	})
+	cache, err := openCache(ctx, cfg.CacheDir, cfg.NoCache)
+	if err != nil {
+		return err
+	}
+	defer closeCache(cache)
+
+	sc, err := newScorer(cfg, cache)
+	if err != nil {
+		return err
+	}
 
 	// Concurrency settings
 	concurrency := cfg.Concurrency
@@ -124,11 +526,52 @@ func Score(ctx context.Context, cfg *ScoreConfig) error {
 		concurrency = 1
 	}
 
+	// Create the output, tweets are streamed if the format supports it
+	out, err := newOutput[*Tweet](cfg.Output, cfg.Format, cfg.DB != "")
+	if err != nil {
+		return err
+	}
+
 	var idx int
 	var lck sync.Mutex
 
 	var tws []*Tweet
 
+	// Reuse the scores already stored in the database
+	if cfg.DB != "" {
+		db, err := store.Open(ctx, cfg.DB)
+		if err != nil {
+			return err
+		}
+		scores, err := db.Scores(ctx, store.KindScore, sc.model, sc.key)
+		_ = db.Close()
+		if err != nil {
+			return err
+		}
+		// Scores stored without details are asked again if the output needs
+		// them
+		detailed := sc.structured || sc.logprobs || len(sc.models) > 1
+		var pending []*twitter.Post
+		for _, post := range posts {
+			if score, ok := scores[post.ID]; ok && (score.Data != "" || !detailed) {
+				tw := newTweet(post, score.Score)
+				if err := tw.setDetails(score.Data); err != nil {
+					return err
+				}
+				tws = append(tws, tw)
+				if err := out.stream(tw); err != nil {
+					return err
+				}
+				continue
+			}
+			pending = append(pending, post)
+		}
+		if len(tws) > 0 {
+			log.Printf("reusing %d scores from %s\n", len(tws), cfg.DB)
+		}
+		posts = pending
+	}
+
 	// Launch concurrent ai completions
 	concurrent(ctx, concurrency,
 		func() (*twitter.Post, bool) {
@@ -142,36 +585,14 @@ func Score(ctx context.Context, cfg *ScoreConfig) error {
 		},
 		func(post *twitter.Post) error {
 			// Ask for a score
-			resp, err := c.ChatCompletion(ctx, "Rate the following tweet from 1 to 10 based on relevance, clarity, engagement, and impact. Only answer with a number.\n\n"+post.Text)
+			tw, err := sc.score(ctx, post)
 			if err != nil {
 				return err
 			}
-			match := numberRegex.FindString(resp)
-			var n int
-			if match == "" {
-				log.Println("no number found in response")
-			} else {
-				candidate, err := strconv.Atoi(match)
-				if err != nil {
-					log.Println("error parsing number from response")
-				}
-				n = candidate
-			}
 			lck.Lock()
 			defer lck.Unlock()
-			tws = append(tws, &Tweet{
-				Score: n,
-
-				Comments: post.Comments,
-				Retweets: post.Retweets,
-				Likes:    post.Likes,
-				Views:    post.Views,
-
-				Time: post.Time,
-				Text: post.Text,
-				Link: fmt.Sprintf("https://x.com/%s/status/%s", post.UserID, post.ID),
-			})
-			return nil
+			tws = append(tws, tw)
+			return out.stream(tw)
 		},
 	)
 
@@ -180,19 +601,21 @@ func Score(ctx context.Context, cfg *ScoreConfig) error {
 		return tws[i].Score > tws[j].Score || (tws[i].Score == tws[j].Score && tws[i].Views > tws[j].Views)
 	})
 
-	// Marshal tweets to CSV
-	data, err := gocsv.MarshalBytes(&tws)
-	if err != nil {
-		return fmt.Errorf("couldn't marshal tweets to csv: %w", err)
+	// Report the disagreement between the models of the ensemble
+	if len(sc.models) > 1 {
+		reportDisagreement(sc.models, tws, cfg.Disagreement)
 	}
-	// Write to file if output is provided
-	if cfg.Output != "" {
-		if err := os.WriteFile(cfg.Output, data, 0644); err != nil {
-			return fmt.Errorf("couldn't write tweets to file: %w", err)
+
+	// Store scores in the database if provided
+	if cfg.DB != "" {
+		if err := storeScores(ctx, cfg.DB, store.KindScore, sc.model, sc.key, tws); err != nil {
+			return err
 		}
-		fmt.Println("created file:", cfg.Output)
-	} else {
-		fmt.Println(string(data))
+	}
+
+	// Write tweets to the output
+	if err := out.close(tws); err != nil {
+		return err
 	}
 	return nil
 }
@@ -201,56 +624,84 @@ type EloConfig struct {
 	Debug       bool
 	Concurrency int
 	Input       string
+	Users       bool
 	Output      string
-	Iterations  int
+	Format      string
+	Budget      int
+	Pairing     string
+	Converge    float64
+	TopK        int
+	Patience    int
 	Model       string
 	Host        string
 	Prompt      string
+	PromptFile  string
 	Token       string
+	DB          string
+	Validate    bool
+	Order       string
+	Examples    string
+	LogProbs    bool
+	CacheDir    string
+	NoCache     bool
+	Algorithm   string
+	K           float64
 }
 
 func Elo(ctx context.Context, cfg *EloConfig) error {
 	log.Println("running")
 	defer log.Println("finished")
 
-	var posts []*twitter.Post
-	b, err := os.ReadFile(cfg.Input)
+	posts, err := readPosts(ctx, cfg.Input, cfg.DB, cfg.Users)
 	if err != nil {
-		return fmt.Errorf("couldn't read tweets from file: %w", err)
-	}
-	if err := gocsv.UnmarshalBytes(b, &posts); err != nil {
-		return fmt.Errorf("couldn't unmarshal tweets from csv: %w", err)
+		return err
 	}
 	if len(posts) < 2 {
 		return fmt.Errorf("need at least 2 tweets to compare")
 	}
 
-	c := openai.New(&openai.Config{
-		Debug: cfg.Debug,
-		Model: cfg.Model,
-		Host:  cfg.Host,
-		Token: cfg.Token,
+	cache, err := openCache(ctx, cfg.CacheDir, cfg.NoCache)
+	if err != nil {
+		return err
+	}
+	defer closeCache(cache)
+
+	jdg, err := newJudge(cfg, cache)
+	if err != nil {
+		return err
+	}
+
+	out, err := newOutput[*Tweet](cfg.Output, cfg.Format, cfg.DB != "")
+	if err != nil {
+		return err
+	}
+
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = rank.Elo
+	}
+	ranker, err := rank.New(&rank.Config{
+		Algorithm: cfg.Algorithm,
+		N:         len(posts),
+		K:         cfg.K,
 	})
+	if err != nil {
+		return err
+	}
 
 	var tws []*Tweet
 	for _, post := range posts {
-		tws = append(tws, &Tweet{
-			Score: 1200,
-
-			Comments: post.Comments,
-			Retweets: post.Retweets,
-			Likes:    post.Likes,
-			Views:    post.Views,
+		tws = append(tws, newTweet(post, 0))
+	}
 
-			Time: post.Time,
-			Text: post.Text,
-			Link: fmt.Sprintf("https://x.com/%s/status/%s", post.UserID, post.ID),
-		})
+	pairer, err := rank.NewPairer(cfg.Pairing, len(tws))
+	if err != nil {
+		return err
 	}
 
-	iterations := cfg.Iterations
-	if iterations < 1 {
-		iterations = 1
+	// Total number of comparisons, defaults to 10 per tweet
+	budget := cfg.Budget
+	if budget < 1 {
+		budget = 10 * len(tws)
 	}
 
 	// Concurrency settings
@@ -259,93 +710,313 @@ func Elo(ctx context.Context, cfg *EloConfig) error {
 		concurrency = 1
 	}
 
-	var idx int
-	var currIteration int
 	var lck sync.Mutex
 
-	// Run concurrent ai completions
-	concurrent(ctx, concurrency,
-		func() (*Tweet, bool) {
-			if idx >= len(tws) {
-				idx = 0
-				currIteration++
-				if currIteration >= iterations {
-					return nil, false
-				}
-			}
-			tw := tws[idx]
-			log.Printf("ai: iteration %d/%d, tweet %d/%d\n", currIteration+1, iterations, idx+1, len(tws))
-			idx++
-			return tw, true
-		},
-		func(a *Tweet) error {
-			// Choose a random tweet to compare against
-			var b *Tweet
-			for {
-				b = tws[rand.Intn(len(posts))]
-				if b.Link != a.Link {
-					break
-				}
-			}
-
-			// Make the comparison
-			resp, err := c.ChatCompletion(ctx, cfg.Prompt+"\n\nTWEET 1: "+a.Link+"\n\nTWEET 2: "+b.Link)
-			if err != nil {
-				return err
-			}
-			match := numberRegex.FindString(resp)
-			if match == "" {
-				log.Println(resp)
-				return errors.New("no number found in response")
-			}
-			n, err := strconv.Atoi(match)
-			if err != nil {
-				log.Println(resp)
-				return fmt.Errorf("error parsing number from response: %w", err)
-			}
-			if n != 1 && n != 2 {
-				log.Println(resp)
-				return errors.New("invalid number found in response")
-			}
-			scoreA := 1.0
-			scoreB := 0.0
-			if n == 2 {
-				scoreA = 0.0
-				scoreB = 1.0
-			}
+	// Only successful comparisons count against the budget
+	var done, failed int
 
-			// Update Elo ratings
+	// compare compares the pair of tweets and updates the ratings
+	compare := func(p [2]int) error {
+		i, j := p[0], p[1]
+		scoreA, err := jdg.compare(ctx, tws[i].post, tws[j].post)
+		if err != nil {
 			lck.Lock()
 			defer lck.Unlock()
-			newRatingA, newRatingB := updateEloRatings(float64(a.Score), float64(b.Score), scoreA, scoreB)
-			a.Score = int(newRatingA)
-			b.Score = int(newRatingB)
+			failed++
+			return err
+		}
+
+		// Update ratings
+		lck.Lock()
+		defer lck.Unlock()
+		done++
+		if cfg.Validate {
 			return nil
-		},
-	)
+		}
+		ranker.Update(i, j, scoreA)
+		return nil
+	}
+
+	patience := cfg.Patience
+	if patience < 1 {
+		patience = 1
+	}
+	topK := cfg.TopK
+	if topK < 1 || topK > len(tws) {
+		topK = len(tws)
+	}
+
+	// Run rounds of concurrent ai completions until the budget is spent or
+	// the ranking converges
+	var stable int
+	var curve []string
+	ratings := ranker.Ratings()
+	for round := 1; done < budget && ctx.Err() == nil; round++ {
+		pairs := pairer.Round(ratings)
+		if len(pairs) == 0 {
+			break
+		}
+		if len(pairs) > budget-done {
+			pairs = pairs[:budget-done]
+		}
+		start := done
+		var idx int
+		concurrent(ctx, concurrency,
+			func() ([2]int, bool) {
+				if idx >= len(pairs) {
+					return [2]int{}, false
+				}
+				p := pairs[idx]
+				idx++
+				log.Printf("ai: round %d, comparison %d/%d\n", round, start+idx, budget)
+				return p, true
+			},
+			compare,
+		)
+		if done == start {
+			log.Printf("no comparisons succeeded in round %d, stopping\n", round)
+			break
+		}
+
+		// Check the stability of the top ranking against the previous round
+		prev := ratings
+		ratings = ranker.Ratings()
+		if cfg.Validate || cfg.Converge <= 0 {
+			continue
+		}
+		tau := rank.Stability(prev, ratings, topK)
+		curve = append(curve, fmt.Sprintf("%.3f", tau))
+		log.Printf("round %d: kendall tau of top %d = %.3f\n", round, topK, tau)
+		if tau < cfg.Converge {
+			stable = 0
+			continue
+		}
+		stable++
+		if stable >= patience {
+			log.Printf("ranking converged after %d rounds and %d/%d comparisons\n", round, done, budget)
+			break
+		}
+	}
+	if failed > 0 {
+		log.Printf("%d comparisons failed and didn't count against the budget\n", failed)
+	}
+	if len(curve) > 0 {
+		log.Printf("convergence curve: %s\n", strings.Join(curve, " "))
+		if stable < patience {
+			log.Printf("ranking didn't converge after %d/%d comparisons\n", done, budget)
+		}
+	}
+
+	// Report position picks
+	jdg.report()
+	if cfg.Validate {
+		return nil
+	}
+
+	// Set the ratings and their uncertainty
+	for i, r := range ratings {
+		tws[i].Score = round(r.Value)
+		tws[i].Uncertainty = round(r.Deviation)
+	}
 
 	// Order tweets by score and views
 	sort.Slice(tws, func(i, j int) bool {
 		return tws[i].Score > tws[j].Score || (tws[i].Score == tws[j].Score && tws[i].Views > tws[j].Views)
 	})
 
-	// Marshal tweets to CSV
-	data, err := gocsv.MarshalBytes(&tws)
+	// Store ratings in the database if provided
+	if cfg.DB != "" {
+		if err := storeScores(ctx, cfg.DB, cfg.Algorithm, cfg.Model, jdg.key, tws); err != nil {
+			return err
+		}
+	}
+
+	// Write tweets to the output
+	if err := out.close(tws); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readPosts reads the posts from the input file or, if no input file is
+// provided, from the database or stdin.
+// Posts read from the input file are stored in the database if provided.
+// If users is set, the input contains users that are converted to posts.
+func readPosts(ctx context.Context, input, dbPath string, users bool) ([]*twitter.Post, error) {
+	if users {
+		return readUsers(input, dbPath)
+	}
+	if input == "" && dbPath != "" {
+		db, err := store.Open(ctx, dbPath)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		return db.Posts(ctx)
+	}
+	posts, err := readItems[*twitter.Post](input)
 	if err != nil {
-		return fmt.Errorf("couldn't marshal tweets to csv: %w", err)
+		return nil, err
 	}
-	// Write to file if output is provided
-	if cfg.Output != "" {
-		if err := os.WriteFile(cfg.Output, data, 0644); err != nil {
-			return fmt.Errorf("couldn't write tweets to file: %w", err)
+	// Only add the new posts, metrics of files may be stale so they aren't
+	// stored as snapshots
+	if dbPath != "" {
+		db, err := store.Open(ctx, dbPath)
+		if err != nil {
+			return nil, err
 		}
-		fmt.Println("created file:", cfg.Output)
-	} else {
-		fmt.Println(string(data))
+		defer db.Close()
+		if err := db.AddPosts(ctx, posts); err != nil {
+			return nil, err
+		}
+	}
+	return posts, nil
+}
+
+// readUsers reads the users from the input file and converts them to posts,
+// so accounts can be scored like tweets.
+func readUsers(input, dbPath string) ([]*twitter.Post, error) {
+	if dbPath != "" {
+		return nil, fmt.Errorf("users can't be stored in the database")
+	}
+	users, err := readItems[*twitter.User](input)
+	if err != nil {
+		return nil, err
+	}
+	var posts []*twitter.Post
+	for _, u := range users {
+		if u.ID == "" {
+			continue
+		}
+		posts = append(posts, userPost(u))
+	}
+	return posts, nil
+}
+
+// userPost converts a user to a post with the bio as text.
+// The handle is used as the post id, so results link to the profile.
+func userPost(u *twitter.User) *twitter.Post {
+	return &twitter.Post{
+		ID:            u.ID,
+		Text:          u.Bio,
+		Time:          u.Joined,
+		UserID:        u.ID,
+		UserName:      u.Name,
+		UserFollowers: u.Followers,
+	}
+}
+
+// storePosts upserts the posts in the database.
+func storePosts(ctx context.Context, dbPath string, posts []*twitter.Post) error {
+	db, err := store.Open(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.UpsertPosts(ctx, posts)
+}
+
+// storeScores saves the scores of the tweets in the database.
+func storeScores(ctx context.Context, dbPath, kind, model, prompt string, tws []*Tweet) error {
+	db, err := store.Open(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var scores []*store.Score
+	for _, tw := range tws {
+		data, err := tw.details()
+		if err != nil {
+			return err
+		}
+		scores = append(scores, &store.Score{
+			PostID: tw.post.ID,
+			Kind:   kind,
+			Model:  model,
+			Prompt: prompt,
+			Score:  tw.Score,
+			Data:   data,
+		})
+	}
+	return db.SaveScores(ctx, scores)
+}
+
+// scoreDetails are the fields of a tweet stored along with its score, so they
+// are kept when the score is reused.
+type scoreDetails struct {
+	Uncertainty  float64 `json:"uncertainty,omitempty"`
+	Confidence   float64 `json:"confidence,omitempty"`
+	Criteria     Scores  `json:"criteria,omitempty"`
+	Rationale    string  `json:"rationale,omitempty"`
+	Models       Scores  `json:"models,omitempty"`
+	Disagreement float64 `json:"disagreement,omitempty"`
+}
+
+// details returns the details of the score as JSON, it is empty if there are
+// no details.
+func (t *Tweet) details() (string, error) {
+	b, err := json.Marshal(&scoreDetails{
+		Uncertainty:  t.Uncertainty,
+		Confidence:   t.Confidence,
+		Criteria:     t.Criteria,
+		Rationale:    t.Rationale,
+		Models:       t.Models,
+		Disagreement: t.Disagreement,
+	})
+	if err != nil {
+		return "", fmt.Errorf("couldn't marshal score details: %w", err)
+	}
+	if string(b) == "{}" {
+		return "", nil
+	}
+	return string(b), nil
+}
+
+// setDetails sets the details of the score from JSON.
+func (t *Tweet) setDetails(data string) error {
+	if data == "" {
+		return nil
+	}
+	var d scoreDetails
+	if err := json.Unmarshal([]byte(data), &d); err != nil {
+		return fmt.Errorf("couldn't unmarshal score details: %w", err)
 	}
+	t.Uncertainty = d.Uncertainty
+	t.Confidence = d.Confidence
+	t.Criteria = d.Criteria
+	t.Rationale = d.Rationale
+	t.Models = d.Models
+	t.Disagreement = d.Disagreement
 	return nil
 }
 
+// openCache opens the cache of AI responses, it returns nil if the cache is
+// disabled.
+// If no dir is provided, the user cache dir is used.
+func openCache(ctx context.Context, dir string, disabled bool) (*openai.Cache, error) {
+	if disabled {
+		return nil, nil
+	}
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get user cache dir: %w", err)
+		}
+		dir = filepath.Join(base, "twai")
+	}
+	return openai.OpenCache(ctx, dir)
+}
+
+// closeCache logs the cache hits and misses and closes the cache.
+func closeCache(cache *openai.Cache) {
+	if cache == nil {
+		return
+	}
+	hits, misses := cache.Stats()
+	log.Printf("cache: %d hits, %d misses\n", hits, misses)
+	_ = cache.Close()
+}
+
 // Generic concurrent function
 func concurrent[T any](ctx context.Context, n int, next func() (T, bool), fn func(T) error) {
 	errC := make(chan error, n)
@@ -398,23 +1069,58 @@ func concurrent[T any](ctx context.Context, n int, next func() (T, bool), fn fun
 	wg.Wait()
 }
 
+// parseTime parses a time in RFC3339 or date (2006-01-02) format.
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
 var numberRegex = regexp.MustCompile(`\d+`)
 
-// Constants for the K-factor
-const K = 32
+var (
+	tieRegex        = regexp.MustCompile(`\b(tie|draw|both bad|both good|neither|equal|equally)\b`)
+	leadingTieRegex = regexp.MustCompile(`^\W*(tie|draw|both bad|both good|neither|equal|equally)\b`)
+	slightRegex     = regexp.MustCompile(`\b(slightly|somewhat|a bit|a little)\b`)
+)
 
-// Function to calculate the expected score
-func expectedScore(ratingA, ratingB float64) float64 {
-	return 1.0 / (1.0 + math.Pow(10, (ratingB-ratingA)/400))
+// parseOutcome parses the answer of a pairwise comparison and returns the
+// score of tweet 1.
+// Answers can be "1" or "2", "tie" or "both bad" for a draw, and graded
+// preferences like "1 much better" (1.0) or "2 slightly better" (0.25).
+func parseOutcome(resp string) (float64, error) {
+	resp = strings.ToLower(resp)
+	// Answers starting with a tie are a draw, tie words after a winner are
+	// part of the explanation (e.g. "1 is better; 2 is not equally clear")
+	if leadingTieRegex.MatchString(resp) {
+		return 0.5, nil
+	}
+	match := numberRegex.FindString(resp)
+	if match == "" {
+		if tieRegex.MatchString(resp) {
+			return 0.5, nil
+		}
+		return 0, errors.New("no number found in response")
+	}
+	margin := 1.0
+	if slightRegex.MatchString(resp) {
+		margin = 0.75
+	}
+	switch match {
+	case "1":
+		return margin, nil
+	case "2":
+		return 1.0 - margin, nil
+	default:
+		return 0, errors.New("invalid number found in response")
+	}
 }
 
-// Function to update Elo ratings
-func updateEloRatings(ratingA, ratingB, scoreA, scoreB float64) (float64, float64) {
-	expectedA := expectedScore(ratingA, ratingB)
-	expectedB := expectedScore(ratingB, ratingA)
-
-	newRatingA := ratingA + K*(scoreA-expectedA)
-	newRatingB := ratingB + K*(scoreB-expectedB)
-
-	return newRatingA, newRatingB
+// round rounds a rating to two decimals.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
 }
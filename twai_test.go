@@ -0,0 +1,216 @@
+package twai
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/igolaizola/twai/pkg/twitter"
+)
+
+func TestParseOutcome(t *testing.T) {
+	tests := []struct {
+		resp    string
+		want    float64
+		wantErr bool
+	}{
+		{resp: "1", want: 1},
+		{resp: "2", want: 0},
+		{resp: "Tweet 1", want: 1},
+		{resp: "1 much better", want: 1},
+		{resp: "2 slightly better", want: 0.25},
+		{resp: "1 is a bit better", want: 0.75},
+		{resp: "Tie", want: 0.5},
+		{resp: "**Draw**", want: 0.5},
+		{resp: "tie, 1 and 2 are equal", want: 0.5},
+		{resp: "Both are equally good", want: 0.5},
+		{resp: "neither", want: 0.5},
+		{resp: "1 is better; 2 is not equally clear", want: 1},
+		{resp: "2, although both bad", want: 0},
+		{resp: "3", wantErr: true},
+		{resp: "I can't decide", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resp, func(t *testing.T) {
+			got, err := parseOutcome(tt.resp)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	tests := []struct {
+		resp    string
+		want    float64
+		wantErr bool
+	}{
+		{resp: "8", want: 8},
+		{resp: "10", want: 10},
+		{resp: "7.5", want: 7.5},
+		{resp: "On a scale of 1-10: 8", want: 8},
+		{resp: "On a scale of 1 to 10, I'd rate it 6", want: 6},
+		{resp: "Score: 7/10", want: 7},
+		{resp: "8 out of 10", want: 8},
+		{resp: "Rating (1–10): 9. It is clear and engaging.", want: 9},
+		{resp: "I'd rate it 4 because it lacks context", want: 4},
+		{resp: "Note: the tweet is clear. Score 5", want: 5},
+		{resp: "8\n\nReason: it announces Go 1.22", want: 8},
+		{resp: "8. Explanation: clear, 2 hashtags", want: 8},
+		{resp: "Score: 7. Relevance: 9", want: 7},
+		{resp: "I can't rate this tweet", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resp, func(t *testing.T) {
+			got, err := parseScore(tt.resp)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbsScore(t *testing.T) {
+	tests := []struct {
+		name           string
+		probs          map[string]float64
+		scale          rubricScale
+		want           float64
+		wantConfidence float64
+		wantOK         bool
+	}{
+		{
+			name:           "expected score",
+			probs:          map[string]float64{"8": 0.5, "6": 0.25, "10": 0.25},
+			scale:          defaultScale,
+			want:           8,
+			wantConfidence: 0.5,
+			wantOK:         true,
+		},
+		{
+			name:           "out of scale",
+			probs:          map[string]float64{"8": 0.4, "0": 0.2, "11": 0.1, "100": 0.1, "-1": 0.1, "Score": 0.1},
+			scale:          defaultScale,
+			want:           8,
+			wantConfidence: 1,
+			wantOK:         true,
+		},
+		{
+			name:           "rubric scale",
+			probs:          map[string]float64{"0": 0.5, "4": 0.25, "5": 0.25},
+			scale:          rubricScale{Min: 0, Max: 4},
+			want:           4.0 / 3,
+			wantConfidence: 2.0 / 3,
+			wantOK:         true,
+		},
+		{
+			name:   "no answers in scale",
+			probs:  map[string]float64{"0": 0.5, "11": 0.3, "The": 0.2},
+			scale:  defaultScale,
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, confidence, ok := probsScore(tt.probs, tt.scale)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if math.Abs(confidence-tt.wantConfidence) > 1e-9 {
+				t.Errorf("got confidence %v, want %v", confidence, tt.wantConfidence)
+			}
+		})
+	}
+}
+
+func TestScoreDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		tweet    *Tweet
+		wantData bool
+	}{
+		{name: "no details", tweet: &Tweet{Score: 8}},
+		{
+			name: "structured",
+			tweet: &Tweet{
+				Score:      8,
+				Confidence: 0.9,
+				Criteria:   Scores{"relevance": 9, "clarity": 7},
+				Rationale:  "clear and relevant",
+			},
+			wantData: true,
+		},
+		{
+			name: "ensemble",
+			tweet: &Tweet{
+				Score:        7,
+				Models:       Scores{"llama3": 8, "gpt-4o": 6},
+				Disagreement: 1,
+			},
+			wantData: true,
+		},
+		{name: "rating", tweet: &Tweet{Score: 1516, Uncertainty: 80}, wantData: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.tweet.details()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (data != "") != tt.wantData {
+				t.Fatalf("got data %q, want data %v", data, tt.wantData)
+			}
+			got := &Tweet{Score: tt.tweet.Score}
+			if err := got.setDetails(data); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.tweet) {
+				t.Errorf("got %+v, want %+v", got, tt.tweet)
+			}
+		})
+	}
+}
+
+func TestNextWatermark(t *testing.T) {
+	posts := []*twitter.Post{{ID: "1750000000000000002"}, {ID: "1750000000000000003"}, {ID: "1750000000000000001"}}
+	tests := []struct {
+		name      string
+		watermark string
+		complete  bool
+		want      string
+	}{
+		{name: "first run", watermark: "", complete: false, want: "1750000000000000003"},
+		{name: "watermark reached", watermark: "1740000000000000000", complete: true, want: "1750000000000000003"},
+		{name: "stopped before the watermark", watermark: "1740000000000000000", complete: false, want: "1740000000000000000"},
+		{name: "no newer posts", watermark: "1760000000000000000", complete: true, want: "1760000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextWatermark(tt.watermark, posts, tt.complete); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}